@@ -0,0 +1,4 @@
+package client
+
+// Типизированный клиент по api/openapi.yaml; после изменения спецификации - go generate ./api/...
+//go:generate oapi-codegen --config=config.yaml ../openapi.yaml
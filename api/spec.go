@@ -0,0 +1,32 @@
+// Package api содержит OpenAPI-спецификацию HTTP API сервиса. Клиент по ней
+// генерируется в api/client; routes_test.go в cmd/server сверяет ее с роутером.
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// YAML возвращает спецификацию в исходном виде.
+func YAML() []byte {
+	return spec
+}
+
+// JSON возвращает спецификацию в JSON - в таком виде она отдается на /openapi.json.
+func JSON() ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi.yaml: %v", err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("encode openapi.json: %v", err)
+	}
+	return data, nil
+}
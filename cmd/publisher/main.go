@@ -1,21 +1,77 @@
 package main
 
 import (
+    "context"
     "encoding/json"
+    "flag"
     "fmt"
     "log"
+    "os"
+    "time"
+    "order-service/internal/broker"
     "order-service/internal/config"
+    "order-service/internal/envelope"
+    "order-service/internal/tracing"
+    "github.com/nats-io/nats.go"
+    "github.com/nats-io/nats.go/jetstream"
     "github.com/nats-io/stan.go"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
 )
 
 func main() {
-    cfg := config.Load()
+    fs := flag.NewFlagSet("publisher", flag.ExitOnError)
+    useEnvelope := fs.Bool("envelope", true, "wrap the order in a versioned envelope (false sends a legacy bare order)")
+    // Подписчик отклоняет обновление сохраненного заказа без версии или с меньшей версией
+    version := fs.Int64("version", time.Now().UnixMilli(), "order revision (default: current time in milliseconds)")
+    loader := config.NewLoader(fs)
+    fs.Parse(os.Args[1:])
+
+    cfg, err := loader.Load()
+    if err != nil {
+        log.Fatal(err)
+    }
     
-    sc, err := stan.Connect(cfg.NATS.ClusterID, "publisher", stan.NatsURL(cfg.NATS.URL))
+    shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+        Exporter:    cfg.Tracing.Exporter,
+        Endpoint:    cfg.Tracing.Endpoint,
+        ServiceName: "order-publisher",
+    })
     if err != nil {
         log.Fatal(err)
     }
-    defer sc.Close()
+    defer shutdownTracing(context.Background())
+    
+    // Брокер выбирается так же, как у сервера: -nats.broker=jetstream или NATS_BROKER
+    var publish func(data []byte) error
+    switch cfg.NATS.Broker {
+    case "jetstream":
+        nc, err := nats.Connect(cfg.NATS.URL, nats.Name("publisher"))
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer nc.Close()
+        js, err := jetstream.New(nc)
+        if err != nil {
+            log.Fatal(err)
+        }
+        if _, err := broker.EnsureStream(context.Background(), js, cfg.NATS.Stream, cfg.NATS.Subject); err != nil {
+            log.Fatal(err)
+        }
+        publish = func(data []byte) error {
+            _, err := js.Publish(context.Background(), cfg.NATS.Subject, data)
+            return err
+        }
+    default:
+        sc, err := stan.Connect(cfg.NATS.ClusterID, "publisher", stan.NatsURL(cfg.NATS.URL))
+        if err != nil {
+            log.Fatal(err)
+        }
+        defer sc.Close()
+        publish = func(data []byte) error {
+            return sc.Publish(cfg.NATS.Subject, data)
+        }
+    }
     
     // Данные из model.json
     orderData := `{
@@ -73,8 +129,29 @@ func main() {
     if err := json.Unmarshal([]byte(orderData), &order); err != nil {
         log.Fatal("Invalid JSON:", err)
     }
+    order["version"] = *version
+    message, err := json.Marshal(order)
+    if err != nil {
+        log.Fatal(err)
+    }
+    
+    // Контекст трассировки передается в конверте, чтобы span подписчика связался со span публикации.
+    // Голый заказ без конверта подписчик принимает как схему версии 1.
+    ctx, span := tracing.Tracer().Start(context.Background(), "nats.publish",
+        trace.WithSpanKind(trace.SpanKindProducer),
+        trace.WithAttributes(attribute.String("messaging.destination.name", cfg.NATS.Subject)))
     
-    if err := sc.Publish(cfg.NATS.Subject, []byte(orderData)); err != nil {
+    if *useEnvelope {
+        env := envelope.New("order-publisher", message)
+        tracing.Inject(ctx, env.Headers)
+        if message, err = env.Marshal(); err != nil {
+            log.Fatal(err)
+        }
+    }
+    
+    err = publish(message)
+    tracing.End(span, err)
+    if err != nil {
         log.Fatal(err)
     }
     
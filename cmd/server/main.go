@@ -1,81 +1,357 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"order-service/api"
+	"order-service/internal/broker"
 	"order-service/internal/cache"
 	"order-service/internal/config"
+	"order-service/internal/events"
+	"order-service/internal/logging"
 	"order-service/internal/repository"
 	"order-service/internal/service"
+	"order-service/internal/stream"
+	"order-service/internal/tracing"
+	"order-service/internal/webhook"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	graphqlhandler "order-service/internal/delivery/graphql"
+	grpchandler "order-service/internal/delivery/grpc"
 	httphandler "order-service/internal/delivery/http"
 
-	"github.com/gorilla/mux"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/nats-io/stan.go"
+	"google.golang.org/grpc"
 )
 
+// Сколько ждать завершения текущих запросов при остановке
+const shutdownTimeout = 15 * time.Second
+
 func main() {
-	log.Println("Starting Order Service...")
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		printConfig(args[2:])
+		return
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		fatal("Failed to load config", err)
+	}
+
+	if _, err := logging.Setup(logging.Config{
+		Level:            cfg.Logging.Level,
+		DisableRedaction: cfg.Logging.DisableRedaction,
+	}); err != nil {
+		fatal("Failed to set up logging", err)
+	}
 
-	cfg := config.Load()
+	slog.Info("Starting Order Service...")
+
+	// Выход из main, а не os.Exit: отложенные закрытия подписки, брокера, БД и трассировки
+	// должны выполниться. Ненулевой код возврата выставляется последним
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	db, err := repository.NewPostgresDB(repository.DBConfig{
-		Host:     cfg.Database.Host,
-		Port:     cfg.Database.Port,
-		User:     cfg.Database.User,
-		Password: cfg.Database.Password,
-		DBName:   cfg.Database.DBName,
-		SSLMode:  cfg.Database.SSLMode,
+		DSN:             cfg.Database.DSN,
+		Host:            cfg.Database.Host,
+		Port:            cfg.Database.Port,
+		User:            cfg.Database.User,
+		Password:        cfg.Database.Password,
+		PasswordFile:    cfg.Database.PasswordFile,
+		DBName:          cfg.Database.DBName,
+		SSLMode:         cfg.Database.SSLMode,
+		SSLRootCert:     cfg.Database.SSLRootCert,
+		SSLCert:         cfg.Database.SSLCert,
+		SSLKey:          cfg.Database.SSLKey,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
 	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
 	}
 	defer db.Close()
 
 	repo := repository.NewOrderRepository(db)
 	cache := cache.New()
+	// Каждое сохранение заказа попадает в /orders/stream
+	hub := stream.NewHub(cfg.Stream.BufferSize, 64)
+	cache.OnSet(hub.Publish)
 
 	// Optimization
 	orders, err := repo.GetAllOrders()
 	if err != nil {
-		log.Printf("Error restoring cache from DB: %v", err)
+		slog.Error("Error restoring cache from DB", "error", err)
 	} else {
 		cache.Restore(orders)
-		log.Printf("Cache restored with %d orders", len(orders))
+		slog.Info("Cache restored", "orders", len(orders))
 	}
 
 	// Optimization
-	log.Printf("Connecting to NATS: %s", cfg.NATS.URL)
-	sc, err := stan.Connect(cfg.NATS.ClusterID, cfg.NATS.ClientID, stan.NatsURL(cfg.NATS.URL))
+	source, publisher, closeBroker, err := newMessageSource(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to NATS: %v", err)
+		fatal("Failed to connect to NATS", err)
 	}
-	defer sc.Close()
-	log.Println("Connected to NATS successfully")
+	defer closeBroker()
 
 	// Optimization
-	subscriber := service.NewNatsSubscriber(sc, repo, cache, cfg.NATS.Subject)
-	sub, err := subscriber.Subscribe()
-	if err != nil {
-		log.Fatalf("Failed to subscribe: %v", err)
+	subscriber := service.NewSubscriber(source, repo, cache)
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Получатели событий из outbox: брокер и вебхуки
+	var sinks []events.Publisher
+	if publisher != nil {
+		sinks = append(sinks, publisher)
+	}
+	var webhooks httphandler.WebhookRegistry
+	if cfg.Webhooks.Enabled {
+		dispatcher := webhook.NewDispatcher(repository.NewWebhookRepository(db), webhook.Options{
+			Interval:    cfg.Webhooks.Interval,
+			Timeout:     cfg.Webhooks.Timeout,
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			Backoff:     cfg.Webhooks.Backoff,
+			MaxBackoff:  cfg.Webhooks.MaxBackoff,
+			Concurrency: cfg.Webhooks.Concurrency,
+		})
+		sinks = append(sinks, dispatcher)
+		webhooks = dispatcher
+		go dispatcher.Run(background)
 	}
-	defer sub.Unsubscribe()
-	log.Printf("Subscribed to subject: %s", cfg.NATS.Subject)
 
-	// Optimization
-	handler := httphandler.NewHandler(cache)
-	router := mux.NewRouter()
+	// События пишут и подписчик, и PATCH позиций
+	orderService := service.NewOrderService(repo, cache)
+	if cfg.Events.Enabled && len(sinks) == 0 {
+		slog.Warn("Order events are disabled without a message broker or webhooks")
+	} else if cfg.Events.Enabled {
+		emitter := events.NewEmitter(events.Subjects{
+			Created:       cfg.Events.CreatedSubject,
+			Updated:       cfg.Events.UpdatedSubject,
+			StatusChanged: cfg.Events.StatusChangedSubject,
+			Rejected:      cfg.Events.RejectedSubject,
+		})
+		subscriber.SetEventEmitter(emitter)
+		orderService.SetEventEmitter(emitter)
+		relay := events.NewRelay(repo, events.Fanout(sinks...), events.RelayOptions{
+			Interval:        cfg.Events.RelayInterval,
+			BatchSize:       cfg.Events.BatchSize,
+			Retention:       cfg.Events.Retention,
+			CleanupInterval: cfg.Events.CleanupInterval,
+		})
+		go relay.Run(background)
+	}
+	if err := subscriber.Start(context.Background(), cfg.NATS.Workers, cfg.NATS.QueueSize); err != nil {
+		fatal("Failed to subscribe", err)
+	}
+	defer subscriber.Close()
+	slog.Info("Subscribed to subject", "subject", cfg.NATS.Subject, "broker", cfg.NATS.Broker, "workers", cfg.NATS.Workers)
 
-	// Optimization
-	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/",
-		http.FileServer(http.Dir("web/static/"))))
+	// Настройки, которые можно менять без перезапуска (SIGHUP или изменение файла)
+	rateLimiter := httphandler.NewRateLimiter()
+	adminAuth := httphandler.NewTokenAuth()
+	applyRuntime := func(cfg *config.Config) {
+		if err := logging.SetLevel(cfg.Logging.Level); err != nil {
+			slog.Error("Failed to apply log level", "error", err)
+		}
+		cache.SetLimit(cfg.Cache.MaxOrders)
+		rateLimiter.SetLimit(cfg.HTTP.RateLimit, cfg.HTTP.RateBurst)
+		adminAuth.SetToken(cfg.Admin.Token)
+		subscriber.SetStrictValidation(cfg.Validation.Strict)
+		subscriber.SetStrictDecoding(cfg.Decoding.Strict)
+	}
+	applyRuntime(cfg)
+	if cfg.Admin.Token == "" {
+		slog.Warn("Admin API is disabled: admin.token is not set")
+	}
+
+	configStore := config.NewStore(cfg, args)
+	configStore.OnReload(applyRuntime)
+	go configStore.Watch(ctx, 5*time.Second)
 
 	// Optimization
-	router.HandleFunc("/orders/{id}", handler.GetOrder).Methods("GET")
-	router.HandleFunc("/orders", handler.GetOrders).Methods("GET")
-	router.HandleFunc("/health", handler.HealthCheck).Methods("GET")
-	router.HandleFunc("/", handler.ServeOrderPage)
+	handler := httphandler.NewHandler(cache, repo)
+	itemHandler := httphandler.NewItemHandler(orderService)
+	adminHandler := httphandler.NewAdminHandler(configStore)
+	var replayer httphandler.ReplayRunner
+	if r, ok := source.(broker.Replayer); ok {
+		replayer = service.NewReplayer(subscriber, r, repo)
+	}
+	replayHandler := httphandler.NewReplayHandler(replayer)
+	webhookHandler := httphandler.NewWebhookHandler(webhooks)
+	streamHandler := httphandler.NewStreamHandler(hub)
+	spec, err := api.JSON()
+	if err != nil {
+		fatal("Failed to load OpenAPI spec", err)
+	}
+	router := newRouter(routes{
+		orders:   handler,
+		items:    itemHandler,
+		admin:    adminHandler,
+		replay:   replayHandler,
+		webhooks: webhookHandler,
+		stream:   streamHandler,
+		docs:     httphandler.NewDocsHandler(spec),
+		graphql:  graphqlhandler.NewHandler(cache, repo),
+
+		adminAuth: adminAuth.Middleware,
+	}, httphandler.Tracing, httphandler.Logging, httphandler.Compress, rateLimiter.Middleware)
+
+	serveErr := make(chan error, 2)
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Address != "" {
+		listener, err := net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			fatal("Failed to listen for gRPC", err)
+		}
+		grpcServer = grpchandler.NewGRPCServer(grpchandler.NewServer(cache, repo, hub))
+		slog.Info("gRPC server starting", "address", cfg.GRPC.Address)
+		go func() { serveErr <- fmt.Errorf("gRPC server stopped: %v", grpcServer.Serve(listener)) }()
+	}
+
+	server := &http.Server{Addr: cfg.HTTP.Address, Handler: router}
+	slog.Info("HTTP server starting", "address", cfg.HTTP.Address)
+	go func() { serveErr <- fmt.Errorf("HTTP server stopped: %v", server.ListenAndServe()) }()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+	case err := <-serveErr:
+		slog.Error("Server failed, shutting down", "error", err)
+		exitCode = 1
+	}
+	// Повторный сигнал завершает процесс сразу
+	stop()
+	shutdownServers(server, grpcServer)
+}
+
+// shutdownServers дожидается текущих запросов не дольше shutdownTimeout, затем закрывает
+// оставшиеся соединения (потоки /orders/stream и WatchOrders сами не завершаются).
+func shutdownServers(server *http.Server, grpcServer *grpc.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		defer func() {
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				grpcServer.Stop()
+			}
+		}()
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("HTTP server did not stop in time, closing connections", "error", err)
+		server.Close()
+	}
+}
+
+// newMessageSource подключается к брокеру из nats.broker и возвращает источник заказов
+// и соединение для публикации событий. В режиме none сервис работает без брокера:
+// отдает заказы из БД и кэша, источник в памяти пуст, события не публикуются.
+func newMessageSource(cfg *config.Config) (broker.MessageSource, events.Publisher, func(), error) {
+	inFlight := service.InFlightLimit(cfg.NATS.Workers, cfg.NATS.QueueSize)
+
+	switch cfg.NATS.Broker {
+	case "none":
+		slog.Warn("Running without a message broker")
+		return broker.NewMemory(cfg.NATS.Subject), nil, func() {}, nil
+
+	case "jetstream":
+		slog.Info("Connecting to NATS JetStream", "url", cfg.NATS.URL)
+		nc, err := nats.Connect(cfg.NATS.URL, nats.Name(cfg.NATS.ClientID))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		js, err := jetstream.New(nc)
+		if err != nil {
+			nc.Close()
+			return nil, nil, nil, err
+		}
+		source := broker.NewJetStream(js, cfg.NATS.Subject, broker.JetStreamOptions{
+			Stream:        cfg.NATS.Stream,
+			Durable:       "order-service",
+			AckWait:       cfg.NATS.AckWait,
+			MaxDeliver:    cfg.NATS.MaxDeliver,
+			MaxAckPending: inFlight,
+		})
+		if !cfg.Events.Enabled {
+			return source, nil, nc.Close, nil
+		}
+		// События публикуются с подтверждением в собственный поток: relay отмечает
+		// событие отправленным только после того, как поток его сохранил
+		_, err = broker.EnsureStream(context.Background(), js, cfg.Events.Stream,
+			cfg.Events.CreatedSubject, cfg.Events.UpdatedSubject, cfg.Events.StatusChangedSubject, cfg.Events.RejectedSubject)
+		if err != nil {
+			nc.Close()
+			return nil, nil, nil, err
+		}
+		return source, broker.NewJetStreamPublisher(js, 0), nc.Close, nil
+
+	default:
+		slog.Info("Connecting to NATS Streaming", "url", cfg.NATS.URL)
+		sc, err := stan.Connect(cfg.NATS.ClusterID, cfg.NATS.ClientID, stan.NatsURL(cfg.NATS.URL))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		slog.Info("Connected to NATS successfully")
+		// С воркерами сообщения подтверждаются после обработки
+		source := broker.NewSTAN(sc, cfg.NATS.Subject, broker.STANOptions{
+			Durable:     "order-service",
+			ManualAck:   cfg.NATS.Workers > 0,
+			AckWait:     cfg.NATS.AckWait,
+			MaxInflight: inFlight,
+		})
+		return source, sc, func() { sc.Close() }, nil
+	}
+}
+
+// printConfig печатает действующую конфигурацию: order-service config print [flags]
+func printConfig(args []string) {
+	cfg, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
-	log.Printf("HTTP server starting on %s", cfg.HTTP.Address)
-	log.Fatal(http.ListenAndServe(cfg.HTTP.Address, router))
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
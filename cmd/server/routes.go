@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"order-service/internal/metrics"
+
+	httphandler "order-service/internal/delivery/http"
+
+	"github.com/gorilla/mux"
+)
+
+// routes - обработчики HTTP API. Каждый маршрут newRouter должен быть описан
+// в api/openapi.yaml, иначе падает TestRoutesDocumented.
+type routes struct {
+	orders   *httphandler.Handler
+	items    *httphandler.ItemHandler
+	admin    *httphandler.AdminHandler
+	replay   *httphandler.ReplayHandler
+	webhooks *httphandler.WebhookHandler
+	stream   *httphandler.StreamHandler
+	docs     *httphandler.DocsHandler
+	graphql  http.Handler
+	// Проверка admin.token для /admin/*, /webhooks и изменения статуса позиции
+	adminAuth mux.MiddlewareFunc
+}
+
+func newRouter(h routes, middleware ...mux.MiddlewareFunc) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(middleware...)
+
+	// Optimization
+	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/",
+		http.FileServer(http.Dir("web/static/"))))
+
+	// Optimization
+	router.HandleFunc("/orders/stream", h.stream.Orders).Methods("GET")
+	router.HandleFunc("/orders/{id}", h.orders.GetOrder).Methods("GET")
+	router.HandleFunc("/orders/{id}/status-history", h.orders.GetStatusHistory).Methods("GET")
+	router.Handle("/orders/{id}/items/{rid}/status", h.adminAuth(http.HandlerFunc(h.items.UpdateStatus))).Methods("PATCH")
+	router.HandleFunc("/orders", h.orders.GetOrders).Methods("GET")
+	router.Handle("/graphql", h.graphql).Methods("GET", "POST")
+	router.HandleFunc("/health", h.orders.HealthCheck).Methods("GET")
+	router.Handle("/admin/config", h.adminAuth(http.HandlerFunc(h.admin.Config))).Methods("GET")
+	router.Handle("/admin/replay", h.adminAuth(http.HandlerFunc(h.replay.Replay))).Methods("POST")
+	router.Handle("/webhooks", h.adminAuth(http.HandlerFunc(h.webhooks.Create))).Methods("POST")
+	router.Handle("/webhooks/{id}/deliveries", h.adminAuth(http.HandlerFunc(h.webhooks.Deliveries))).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	router.HandleFunc("/openapi.json", h.docs.Spec).Methods("GET")
+	router.PathPrefix("/docs/").HandlerFunc(h.docs.UI).Methods("GET")
+	router.HandleFunc("/", h.orders.ServeOrderPage)
+	return router
+}
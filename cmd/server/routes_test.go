@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"order-service/api"
+	"order-service/api/client"
+	"order-service/internal/cache"
+	"order-service/internal/config"
+	"order-service/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	httphandler "order-service/internal/delivery/http"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+const adminToken = "s3cret"
+
+// newTestRouter - роутер с admin.token = adminToken
+func newTestRouter(h routes) *mux.Router {
+	auth := httphandler.NewTokenAuth()
+	auth.SetToken(adminToken)
+	h.adminAuth = auth.Middleware
+	return newRouter(h)
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(api.YAML(), &doc); err != nil {
+		t.Fatalf("parse openapi.yaml: %v", err)
+	}
+	// "GET /orders/{id}" -> есть ли такой маршрут
+	operations := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			operations[strings.ToUpper(method)+" "+path] = false
+		}
+	}
+
+	err := newTestRouter(routes{}).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		// Маршрут без Methods отвечает на любой метод, в спецификации он описан как GET
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{"GET"}
+		}
+		pattern, _ := route.GetPathRegexp()
+		prefix := !strings.HasSuffix(pattern, "$")
+
+		for _, method := range methods {
+			op, ok := findOperation(operations, method, path, prefix)
+			if !ok {
+				t.Errorf("%s %s is not documented in api/openapi.yaml", method, path)
+				continue
+			}
+			operations[op] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for op, routed := range operations {
+		if !routed {
+			t.Errorf("%s is documented but not routed", op)
+		}
+	}
+}
+
+// findOperation ищет операцию маршрута; PathPrefix покрывает пути спецификации под префиксом.
+func findOperation(operations map[string]bool, method, path string, prefix bool) (string, bool) {
+	if _, ok := operations[method+" "+path]; ok || !prefix {
+		return method + " " + path, ok
+	}
+	for op := range operations {
+		if strings.HasPrefix(op, method+" "+path) {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func TestDocsRoutes(t *testing.T) {
+	spec, err := api.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newTestRouter(routes{docs: httphandler.NewDocsHandler(spec)}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string                 `json:"openapi"`
+		Paths   map[string]interface{} `json:"paths"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK || doc.OpenAPI != "3.0.3" || doc.Paths["/orders/{id}"] == nil {
+		t.Fatalf("/openapi.json: status %d, err %v, openapi %q", resp.StatusCode, err, doc.OpenAPI)
+	}
+
+	resp, err = http.Get(server.URL + "/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), "/openapi.json") {
+		t.Errorf("/docs/: status %d, body %.200s", resp.StatusCode, page)
+	}
+}
+
+func TestClient_GetOrder(t *testing.T) {
+	cache := cache.New()
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cache.Set(&models.Order{
+		OrderUID:    "test-123",
+		TrackNumber: "TRACK-123",
+		DateCreated: created,
+		Status:      models.StatusPaid,
+		Items:       []models.Item{{ChrtID: 1, Rid: "rid-1", Status: models.ItemShipped}},
+	})
+	server := httptest.NewServer(newTestRouter(routes{orders: httphandler.NewHandler(cache, nil)}))
+	defer server.Close()
+
+	c, err := client.NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.GetOrderWithResponse(context.Background(), "test-123", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+		t.Fatalf("status %d, body %s", resp.StatusCode(), resp.Body)
+	}
+	order := resp.JSON200
+	if order.OrderUid != "test-123" || !order.DateCreated.Equal(created) || order.Status != client.StatusPaid ||
+		len(order.Items) != 1 || order.Items[0].Status != client.ItemStatusShipped {
+		t.Errorf("Unexpected order: %+v", order)
+	}
+
+	// Сгенерированные параметры передают валидаторы
+	etag := resp.HTTPResponse.Header.Get("ETag")
+	again, err := c.GetOrderWithResponse(context.Background(), "test-123", &client.GetOrderParams{IfNoneMatch: &etag})
+	if err != nil || again.StatusCode() != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, err %v", again.StatusCode(), err)
+	}
+
+	missing, err := c.GetOrderWithResponse(context.Background(), "missing", nil)
+	if err != nil || missing.StatusCode() != http.StatusNotFound {
+		t.Errorf("missing order: status %d, err %v", missing.StatusCode(), err)
+	}
+}
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	router := newTestRouter(routes{
+		admin:    httphandler.NewAdminHandler(config.NewStore(config.Default(), nil)),
+		replay:   httphandler.NewReplayHandler(nil),
+		webhooks: httphandler.NewWebhookHandler(nil),
+		items:    httphandler.NewItemHandler(nil),
+	})
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/admin/config", "", http.StatusUnauthorized},
+		{"GET", "/admin/config", "wrong", http.StatusUnauthorized},
+		{"GET", "/admin/config", adminToken, http.StatusOK},
+		{"POST", "/admin/replay", "", http.StatusUnauthorized},
+		{"POST", "/admin/replay", adminToken, http.StatusNotImplemented},
+		{"POST", "/webhooks", "", http.StatusUnauthorized},
+		{"POST", "/webhooks", adminToken, http.StatusNotImplemented},
+		{"GET", "/webhooks/w1/deliveries", "", http.StatusUnauthorized},
+		{"PATCH", "/orders/test-123/items/rid-1/status", "", http.StatusUnauthorized},
+		{"PATCH", "/orders/test-123/items/rid-1/status", adminToken, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s %s with token %q: status %d, want %d", tt.method, tt.path, tt.token, rr.Code, tt.want)
+		}
+	}
+}
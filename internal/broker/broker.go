@@ -0,0 +1,59 @@
+package broker
+
+import (
+	"context"
+	"time"
+)
+
+// Message - сообщение, полученное от брокера.
+type Message interface {
+	Subject() string
+	Sequence() uint64
+	Data() []byte
+	// Redelivered - сообщение доставляется не первый раз
+	Redelivered() bool
+	// Ack подтверждает обработку; брокер больше не доставит сообщение.
+	Ack() error
+	// Nak просит доставить сообщение повторно. Брокеры без явного отказа
+	// повторяют неподтвержденное сообщение по истечении ack wait.
+	Nak() error
+}
+
+// Progresser - сообщение, для которого брокер умеет продлить ack wait, пока
+// обработка повторяется.
+type Progresser interface {
+	InProgress() error
+}
+
+// Handler вызывается для каждого сообщения последовательно, в порядке доставки.
+type Handler func(Message)
+
+// MessageSource - источник сообщений с заказами.
+type MessageSource interface {
+	// Subscribe начинает доставку сообщений в handler. Вызывается один раз.
+	Subscribe(ctx context.Context, handler Handler) error
+	// Close останавливает доставку, не удаляя durable-подписку на сервере.
+	Close() error
+	// System - название брокера для трассировки (messaging.system).
+	System() string
+}
+
+// Position - с какого места перечитать историю: номер сообщения или время.
+type Position struct {
+	Sequence uint64
+	Time     time.Time
+}
+
+// Replayer - источник, который умеет перечитать историю временной подпиской,
+// не затрагивая durable-подписку сервиса. Сообщения повтора подтверждать не нужно.
+type Replayer interface {
+	Replay(ctx context.Context, from Position, handler Handler) (stop func() error, err error)
+}
+
+// replayed - сообщение временной подписки: подтверждение ни на что не влияет.
+type replayed struct {
+	Message
+}
+
+func (replayed) Ack() error { return nil }
+func (replayed) Nak() error { return nil }
@@ -0,0 +1,173 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Задержка повторной доставки после отказа по умолчанию, чтобы не исчерпать
+// max deliver за секунды
+const defaultRedeliveryDelay = 5 * time.Second
+
+// JetStreamOptions - настройки durable pull-консьюмера.
+type JetStreamOptions struct {
+	Stream     string
+	Durable    string
+	AckWait    time.Duration
+	MaxDeliver int
+	// MaxAckPending - сколько сообщений может одновременно ждать подтверждения
+	MaxAckPending int
+	// RedeliveryDelay - через сколько повторить сообщение после Nak
+	RedeliveryDelay time.Duration
+}
+
+type JetStream struct {
+	js      jetstream.JetStream
+	subject string
+	opts    JetStreamOptions
+	cc      jetstream.ConsumeContext
+}
+
+func NewJetStream(js jetstream.JetStream, subject string, opts JetStreamOptions) *JetStream {
+	if opts.MaxAckPending < 1 {
+		opts.MaxAckPending = 1
+	}
+	if opts.RedeliveryDelay <= 0 {
+		opts.RedeliveryDelay = defaultRedeliveryDelay
+	}
+	return &JetStream{js: js, subject: subject, opts: opts}
+}
+
+func (s *JetStream) System() string { return "nats-jetstream" }
+
+// EnsureStream создает поток для subjects или обновляет его. Вызывается и подписчиком,
+// и публикатором, чтобы порядок запуска был не важен.
+func EnsureStream(ctx context.Context, js jetstream.JetStream, stream string, subjects ...string) (jetstream.Stream, error) {
+	s, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     stream,
+		Subjects: subjects,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream %s: %v", stream, err)
+	}
+	return s, nil
+}
+
+// Subscribe создает durable pull-консьюмер с явным подтверждением и начинает получать сообщения.
+func (s *JetStream) Subscribe(ctx context.Context, handler Handler) error {
+	stream, err := EnsureStream(ctx, s.js, s.opts.Stream, s.subject)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       s.opts.Durable,
+		FilterSubject: s.subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       s.opts.AckWait,
+		MaxDeliver:    s.opts.MaxDeliver,
+		MaxAckPending: s.opts.MaxAckPending,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create consumer %s: %v", s.opts.Durable, err)
+	}
+
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		handler(&jetStreamMessage{msg: msg, delay: s.opts.RedeliveryDelay})
+	}, jetstream.PullMaxMessages(s.opts.MaxAckPending))
+	if err != nil {
+		return err
+	}
+	s.cc = cc
+	return nil
+}
+
+func (s *JetStream) Close() error {
+	if s.cc != nil {
+		s.cc.Stop()
+	}
+	return nil
+}
+
+// JetStreamPublisher публикует с подтверждением сервера: Publish возвращается после PubAck,
+// то есть когда поток сохранил сообщение. Publish соединения NATS такого подтверждения не ждет.
+type JetStreamPublisher struct {
+	js      jetstream.JetStream
+	timeout time.Duration
+}
+
+func NewJetStreamPublisher(js jetstream.JetStream, timeout time.Duration) *JetStreamPublisher {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &JetStreamPublisher{js: js, timeout: timeout}
+}
+
+func (p *JetStreamPublisher) Publish(subject string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	if _, err := p.js.Publish(ctx, subject, data); err != nil {
+		return fmt.Errorf("failed to publish to %s: %v", subject, err)
+	}
+	return nil
+}
+
+type jetStreamMessage struct {
+	msg   jetstream.Msg
+	delay time.Duration
+}
+
+func (m *jetStreamMessage) Subject() string { return m.msg.Subject() }
+func (m *jetStreamMessage) Data() []byte    { return m.msg.Data() }
+func (m *jetStreamMessage) Ack() error      { return m.msg.Ack() }
+func (m *jetStreamMessage) Nak() error      { return m.msg.NakWithDelay(m.delay) }
+
+// InProgress сбрасывает таймер ack wait на сервере.
+func (m *jetStreamMessage) InProgress() error { return m.msg.InProgress() }
+
+func (m *jetStreamMessage) Sequence() uint64 {
+	if meta, err := m.msg.Metadata(); err == nil {
+		return meta.Sequence.Stream
+	}
+	return 0
+}
+
+func (m *jetStreamMessage) Redelivered() bool {
+	if meta, err := m.msg.Metadata(); err == nil {
+		return meta.NumDelivered > 1
+	}
+	return false
+}
+
+// Replay читает поток упорядоченным эфемерным консьюмером с заданного номера или времени.
+func (s *JetStream) Replay(ctx context.Context, from Position, handler Handler) (func() error, error) {
+	cfg := jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{s.subject},
+		DeliverPolicy:  jetstream.DeliverByStartSequencePolicy,
+		OptStartSeq:    max(from.Sequence, 1),
+	}
+	if !from.Time.IsZero() {
+		cfg.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		cfg.OptStartSeq = 0
+		cfg.OptStartTime = &from.Time
+	}
+
+	stream, err := s.js.Stream(ctx, s.opts.Stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream %s: %v", s.opts.Stream, err)
+	}
+	consumer, err := stream.OrderedConsumer(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		handler(replayed{&jetStreamMessage{msg: msg}})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() error { cc.Stop(); return nil }, nil
+}
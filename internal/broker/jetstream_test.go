@@ -0,0 +1,187 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// runJetStream запускает встроенный nats-server с JetStream в памяти процесса.
+func runJetStream(t *testing.T) jetstream.JetStream {
+	t.Helper()
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Start()
+	t.Cleanup(srv.Shutdown)
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server did not start")
+	}
+
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return js
+}
+
+func TestJetStream_AckAndNak(t *testing.T) {
+	js := runJetStream(t)
+	ctx := context.Background()
+
+	opts := JetStreamOptions{
+		Stream:          "ORDERS",
+		Durable:         "order-service",
+		AckWait:         time.Second,
+		MaxDeliver:      5,
+		MaxAckPending:   8,
+		RedeliveryDelay: 50 * time.Millisecond,
+	}
+	src := NewJetStream(js, "orders", opts)
+
+	got := make(chan Message, 10)
+	nakked := false
+	if err := src.Subscribe(ctx, func(msg Message) {
+		if string(msg.Data()) == "a" && !nakked {
+			nakked = true
+			msg.Nak()
+			return
+		}
+		msg.Ack()
+		got <- msg
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	for _, data := range []string{"a", "b"} {
+		if _, err := js.Publish(ctx, "orders", []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := map[string]Message{}
+	for len(received) < 2 {
+		select {
+		case msg := <-got:
+			received[string(msg.Data())] = msg
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out, received %d messages", len(received))
+		}
+	}
+	if !received["a"].Redelivered() || received["b"].Redelivered() {
+		t.Error("Expected only the rejected message to be redelivered")
+	}
+	if received["a"].Sequence() != 1 || received["b"].Sequence() != 2 {
+		t.Errorf("Unexpected stream sequences %d, %d", received["a"].Sequence(), received["b"].Sequence())
+	}
+
+	consumer, err := js.Consumer(ctx, opts.Stream, opts.Durable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	var info *jetstream.ConsumerInfo
+	for time.Now().Before(deadline) {
+		if info, err = consumer.Info(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if info.NumAckPending == 0 && info.NumPending == 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if info.NumAckPending != 0 || info.NumPending != 0 {
+		t.Errorf("Expected all messages acked, ack pending %d, pending %d", info.NumAckPending, info.NumPending)
+	}
+	if info.Config.AckPolicy != jetstream.AckExplicitPolicy || info.Config.MaxDeliver != 5 {
+		t.Errorf("Unexpected consumer config: %+v", info.Config)
+	}
+}
+
+func TestJetStream_Replay(t *testing.T) {
+	js := runJetStream(t)
+	ctx := context.Background()
+
+	src := NewJetStream(js, "orders", JetStreamOptions{Stream: "ORDERS", Durable: "order-service"})
+	if _, err := EnsureStream(ctx, js, "ORDERS", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{"a", "b", "c"} {
+		if _, err := js.Publish(ctx, "orders", []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := make(chan Message, 10)
+	stop, err := src.Replay(ctx, Position{Sequence: 2}, func(msg Message) {
+		got <- msg
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	for _, want := range []string{"b", "c"} {
+		select {
+		case msg := <-got:
+			if string(msg.Data()) != want {
+				t.Errorf("Expected %s, got %s", want, msg.Data())
+			}
+			if err := msg.Ack(); err != nil {
+				t.Errorf("Ack of a replayed message should be a no-op, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %s", want)
+		}
+	}
+
+	// Повтор не создает durable-консьюмер
+	if _, err := js.Consumer(ctx, "ORDERS", "order-service"); err == nil {
+		t.Error("Replay must not create the service durable")
+	}
+}
+
+func TestJetStreamPublisher(t *testing.T) {
+	js := runJetStream(t)
+	ctx := context.Background()
+	pub := NewJetStreamPublisher(js, time.Second)
+
+	// Без потока для subject сервер не подтверждает публикацию
+	if err := pub.Publish("orders.events.created", []byte("lost")); err == nil {
+		t.Error("Expected error without a stream for the subject")
+	}
+
+	stream, err := EnsureStream(ctx, js, "ORDER_EVENTS", "orders.events.created", "orders.events.updated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subject := range []string{"orders.events.created", "orders.events.updated"} {
+		if err := pub.Publish(subject, []byte("event")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 2 {
+		t.Errorf("Expected 2 stored events, got %d", info.State.Msgs)
+	}
+}
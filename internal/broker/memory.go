@@ -0,0 +1,180 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrClosed = errors.New("broker: source closed")
+
+// Memory - брокер в памяти процесса для тестов и локального запуска без NATS.
+// Сообщения доставляются по порядку одному подписчику; Nak ставит сообщение
+// в конец очереди для повторной доставки.
+type Memory struct {
+	subject string
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	queue      []*memoryMessage
+	seq        uint64
+	history    []*memoryMessage
+	inFlight   int
+	closed     bool
+	subscribed bool
+	done       chan struct{}
+}
+
+func NewMemory(subject string) *Memory {
+	m := &Memory{subject: subject, done: make(chan struct{})}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+func (m *Memory) System() string { return "memory" }
+
+// Publish ставит сообщение в очередь.
+func (m *Memory) Publish(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.seq++
+	msg := &memoryMessage{src: m, seq: m.seq, data: data, at: time.Now()}
+	m.queue = append(m.queue, msg)
+	m.history = append(m.history, msg)
+	m.cond.Signal()
+	return nil
+}
+
+// Pending возвращает число сообщений в очереди и доставленных, но не подтвержденных.
+func (m *Memory) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue) + m.inFlight
+}
+
+func (m *Memory) Subscribe(ctx context.Context, handler Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	if m.subscribed {
+		return errors.New("broker: memory source supports a single subscriber")
+	}
+	m.subscribed = true
+
+	go func() {
+		defer close(m.done)
+		for {
+			msg := m.next()
+			if msg == nil {
+				return
+			}
+			handler(msg)
+		}
+	}()
+	return nil
+}
+
+func (m *Memory) next() *memoryMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.queue) == 0 && !m.closed {
+		m.cond.Wait()
+	}
+	if m.closed {
+		return nil
+	}
+	msg := m.queue[0]
+	m.queue = m.queue[1:]
+	m.inFlight++
+	return msg
+}
+
+// Close останавливает доставку и ждет, пока handler вернет управление.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	subscribed := m.subscribed
+	m.cond.Broadcast()
+	m.mu.Unlock()
+
+	if subscribed {
+		<-m.done
+	}
+	return nil
+}
+
+// Replay доставляет уже опубликованные сообщения начиная с позиции и останавливается.
+func (m *Memory) Replay(ctx context.Context, from Position, handler Handler) (func() error, error) {
+	m.mu.Lock()
+	var messages []Message
+	for _, msg := range m.history {
+		if msg.seq >= from.Sequence && !msg.at.Before(from.Time) {
+			messages = append(messages, replayed{msg})
+		}
+	}
+	m.mu.Unlock()
+
+	stopped := make(chan struct{})
+	var once sync.Once
+	go func() {
+		for _, msg := range messages {
+			select {
+			case <-stopped:
+				return
+			default:
+			}
+			handler(msg)
+		}
+	}()
+	return func() error { once.Do(func() { close(stopped) }); return nil }, nil
+}
+
+type memoryMessage struct {
+	src         *Memory
+	seq         uint64
+	data        []byte
+	at          time.Time
+	redelivered bool
+	settled     bool
+}
+
+func (m *memoryMessage) Subject() string   { return m.src.subject }
+func (m *memoryMessage) Sequence() uint64  { return m.seq }
+func (m *memoryMessage) Data() []byte      { return m.data }
+func (m *memoryMessage) Redelivered() bool { return m.redelivered }
+
+func (m *memoryMessage) Ack() error {
+	m.src.mu.Lock()
+	defer m.src.mu.Unlock()
+	if !m.settled {
+		m.settled = true
+		m.src.inFlight--
+	}
+	return nil
+}
+
+func (m *memoryMessage) Nak() error {
+	m.src.mu.Lock()
+	defer m.src.mu.Unlock()
+	if m.settled {
+		return nil
+	}
+	m.settled = true
+	m.src.inFlight--
+	if m.src.closed {
+		return ErrClosed
+	}
+	m.src.queue = append(m.src.queue, &memoryMessage{src: m.src, seq: m.seq, data: m.data, at: m.at, redelivered: true})
+	m.src.cond.Signal()
+	return nil
+}
@@ -0,0 +1,86 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemory_DeliversInOrderAndRedelivers(t *testing.T) {
+	src := NewMemory("orders")
+	got := make(chan Message, 10)
+
+	nakked := false
+	if err := src.Subscribe(context.Background(), func(msg Message) {
+		// Первое сообщение отклоняется один раз
+		if msg.Sequence() == 1 && !nakked {
+			nakked = true
+			msg.Nak()
+			return
+		}
+		msg.Ack()
+		got <- msg
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range []string{"a", "b", "c"} {
+		if err := src.Publish([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var order []string
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-got:
+			order = append(order, string(msg.Data()))
+			if msg.Subject() != "orders" {
+				t.Errorf("Expected subject orders, got %s", msg.Subject())
+			}
+			if msg.Redelivered() != (msg.Sequence() == 1) {
+				t.Errorf("Message %d: redelivered = %v", msg.Sequence(), msg.Redelivered())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out, received %v", order)
+		}
+	}
+
+	// Отклоненное сообщение пришло повторно после остальных
+	if want := []string{"b", "c", "a"}; order[0] != want[0] || order[1] != want[1] || order[2] != want[2] {
+		t.Errorf("Expected delivery order %v, got %v", want, order)
+	}
+	if src.Pending() != 0 {
+		t.Errorf("Expected nothing pending, got %d", src.Pending())
+	}
+
+	src.Close()
+	if err := src.Publish([]byte("d")); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestMemory_PendingCountsUnacked(t *testing.T) {
+	src := NewMemory("orders")
+	release := make(chan struct{})
+	src.Subscribe(context.Background(), func(msg Message) {
+		<-release
+		msg.Ack()
+	})
+
+	src.Publish([]byte("a"))
+	src.Publish([]byte("b"))
+	if src.Pending() != 2 {
+		t.Errorf("Expected 2 pending, got %d", src.Pending())
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for src.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if src.Pending() != 0 {
+		t.Errorf("Expected nothing pending, got %d", src.Pending())
+	}
+	src.Close()
+}
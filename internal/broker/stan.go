@@ -0,0 +1,100 @@
+package broker
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/stan.go"
+)
+
+// STANOptions - настройки подписки NATS Streaming.
+type STANOptions struct {
+	Durable string
+	// ManualAck - подтверждать сообщения после обработки; иначе их подтверждает клиент при получении
+	ManualAck   bool
+	AckWait     time.Duration
+	MaxInflight int
+}
+
+type STAN struct {
+	sc      stan.Conn
+	subject string
+	opts    STANOptions
+	sub     stan.Subscription
+}
+
+func NewSTAN(sc stan.Conn, subject string, opts STANOptions) *STAN {
+	return &STAN{sc: sc, subject: subject, opts: opts}
+}
+
+func (s *STAN) System() string { return "nats-streaming" }
+
+func (s *STAN) Subscribe(ctx context.Context, handler Handler) error {
+	var subOpts []stan.SubscriptionOption
+	if s.opts.Durable != "" {
+		subOpts = append(subOpts, stan.DurableName(s.opts.Durable))
+	}
+	if s.opts.ManualAck {
+		subOpts = append(subOpts, stan.SetManualAckMode())
+		if s.opts.AckWait > 0 {
+			subOpts = append(subOpts, stan.AckWait(s.opts.AckWait))
+		}
+		if s.opts.MaxInflight > 0 {
+			subOpts = append(subOpts, stan.MaxInflight(s.opts.MaxInflight))
+		}
+	}
+
+	sub, err := s.sc.Subscribe(s.subject, func(msg *stan.Msg) {
+		handler(&stanMessage{msg: msg, manualAck: s.opts.ManualAck})
+	}, subOpts...)
+	if err != nil {
+		return err
+	}
+	s.sub = sub
+	return nil
+}
+
+func (s *STAN) Close() error {
+	if s.sub == nil {
+		return nil
+	}
+	return s.sub.Close()
+}
+
+type stanMessage struct {
+	msg       *stan.Msg
+	manualAck bool
+}
+
+func (m *stanMessage) Subject() string   { return m.msg.Subject }
+func (m *stanMessage) Sequence() uint64  { return m.msg.Sequence }
+func (m *stanMessage) Data() []byte      { return m.msg.Data }
+func (m *stanMessage) Redelivered() bool { return m.msg.Redelivered }
+
+func (m *stanMessage) Ack() error {
+	if !m.manualAck {
+		return nil
+	}
+	return m.msg.Ack()
+}
+
+// У STAN нет отказа: неподтвержденное сообщение придет повторно через ack wait.
+func (m *stanMessage) Nak() error { return nil }
+
+// Replay открывает временную (не durable) подписку с заданного номера или времени.
+func (s *STAN) Replay(ctx context.Context, from Position, handler Handler) (func() error, error) {
+	start := stan.DeliverAllAvailable()
+	switch {
+	case !from.Time.IsZero():
+		start = stan.StartAtTime(from.Time)
+	case from.Sequence > 0:
+		start = stan.StartAtSequence(from.Sequence)
+	}
+	sub, err := s.sc.Subscribe(s.subject, func(msg *stan.Msg) {
+		handler(replayed{&stanMessage{msg: msg}})
+	}, start)
+	if err != nil {
+		return nil, err
+	}
+	return sub.Unsubscribe, nil
+}
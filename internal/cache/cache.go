@@ -1,25 +1,108 @@
 package cache
 
 import (
+	"container/list"
 	"order-service/internal/models"
 	"sync"
+	"time"
 )
 
 type Cache struct {
 	mu     sync.RWMutex
 	orders map[string]*models.Order
+	// Порядок записи для вытеснения при заданном лимите
+	writes   *list.List
+	elements map[string]*list.Element
+	limit    int
+	onSet    func(*models.Order)
+	// Время последнего изменения набора заказов, включая вытеснение
+	modified time.Time
+	// Был ли вытеснен хотя бы один заказ: после этого кэш не содержит всех заказов
+	truncated bool
+	now       func() time.Time
 }
 
 func New() *Cache {
 	return &Cache{
-		orders: make(map[string]*models.Order),
+		orders:   make(map[string]*models.Order),
+		writes:   list.New(),
+		elements: make(map[string]*list.Element),
+		now:      time.Now,
 	}
 }
 
-func (c *Cache) Set(order *models.Order) {
+// Modified возвращает время последней записи или вытеснения заказа (нулевое для пустого кэша).
+// В отличие от updated_at заказов оно сдвигается и тогда, когда заказ пропадает из кэша.
+func (c *Cache) Modified() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.modified
+}
+
+// SetLimit ограничивает число заказов в кэше (0 - без ограничения).
+// При превышении вытесняются заказы, которые дольше всех не записывались.
+func (c *Cache) SetLimit(limit int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.limit = limit
+	c.evict()
+}
+
+// OnSet задает функцию, которая вызывается для каждого заказа, записанного через Set
+// (Restore ее не вызывает). Вызов идет под блокировкой кэша, чтобы обновления одного
+// заказа приходили по порядку, поэтому функция должна быть быстрой и не обращаться к кэшу.
+func (c *Cache) OnSet(fn func(*models.Order)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onSet = fn
+}
+
+// Set записывает заказ, если он новее закэшированного (см. models.Order.Newer), и сообщает, записан ли он.
+func (c *Cache) Set(order *models.Order) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.set(order) {
+		return false
+	}
+	c.evict()
+	if c.onSet != nil {
+		c.onSet(order)
+	}
+	return true
+}
+
+func (c *Cache) set(order *models.Order) bool {
+	if existing, ok := c.orders[order.OrderUID]; ok && !order.Newer(existing.Version, existing.ContentHash) {
+		return false
+	}
 	c.orders[order.OrderUID] = order
+	c.modified = c.now()
+	if el, ok := c.elements[order.OrderUID]; ok {
+		c.writes.MoveToBack(el)
+		return true
+	}
+	c.elements[order.OrderUID] = c.writes.PushBack(order.OrderUID)
+	return true
+}
+
+func (c *Cache) evict() {
+	for c.limit > 0 && len(c.orders) > c.limit {
+		oldest := c.writes.Front()
+		uid := oldest.Value.(string)
+		c.writes.Remove(oldest)
+		delete(c.elements, uid)
+		delete(c.orders, uid)
+		c.modified = c.now()
+		c.truncated = true
+	}
+}
+
+// Truncated сообщает, вытеснялись ли заказы по лимиту, то есть может ли в кэше не хватать
+// сохраненных заказов. Повышение лимита вытесненные заказы не возвращает, поэтому флаг не сбрасывается.
+func (c *Cache) Truncated() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.truncated
 }
 
 func (c *Cache) Get(uid string) (*models.Order, bool) {
@@ -51,6 +134,7 @@ func (c *Cache) Restore(orders []*models.Order) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	for _, order := range orders {
-		c.orders[order.OrderUID] = order
+		c.set(order)
 	}
+	c.evict()
 }
@@ -51,3 +51,99 @@ func TestCache_Restore(t *testing.T) {
 		t.Errorf("Expected 2 orders, got %d", len(cache.GetAll()))
 	}
 }
+
+func TestCache_SetLimitEvictsOldestWrites(t *testing.T) {
+	cache := New()
+	cache.Set(&models.Order{OrderUID: "order-1"})
+	cache.Set(&models.Order{OrderUID: "order-2"})
+	cache.Set(&models.Order{OrderUID: "order-3"})
+
+	// Перезапись order-1 делает его самым свежим
+	cache.Set(&models.Order{OrderUID: "order-1"})
+	if cache.Truncated() {
+		t.Error("Cache must not be truncated before eviction")
+	}
+	cache.SetLimit(2)
+	if !cache.Truncated() {
+		t.Error("Cache must be truncated after eviction")
+	}
+
+	if _, exists := cache.Get("order-2"); exists {
+		t.Error("order-2 should have been evicted")
+	}
+	for _, uid := range []string{"order-1", "order-3"} {
+		if _, exists := cache.Get(uid); !exists {
+			t.Errorf("%s should remain in cache", uid)
+		}
+	}
+
+	cache.Set(&models.Order{OrderUID: "order-4"})
+	if len(cache.GetAll()) != 2 {
+		t.Errorf("Expected 2 orders, got %d", len(cache.GetAll()))
+	}
+}
+
+func TestCache_Modified(t *testing.T) {
+	cache := New()
+	clock := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return clock }
+
+	if !cache.Modified().IsZero() {
+		t.Errorf("Empty cache Modified = %v", cache.Modified())
+	}
+	cache.Set(&models.Order{OrderUID: "order-1", Version: 1})
+	cache.Set(&models.Order{OrderUID: "order-2", Version: 1})
+	if got := cache.Modified(); !got.Equal(clock) {
+		t.Errorf("Modified after Set = %v", got)
+	}
+
+	// Отклоненная запись время не сдвигает
+	clock = clock.Add(time.Minute)
+	cache.Set(&models.Order{OrderUID: "order-1", Version: 0})
+	if got := cache.Modified(); !got.Equal(clock.Add(-time.Minute)) {
+		t.Errorf("Modified after rejected Set = %v", got)
+	}
+
+	// Вытеснение меняет список без новых updated_at, но время сдвигает
+	cache.SetLimit(1)
+	if got := cache.Modified(); !got.Equal(clock) {
+		t.Errorf("Modified after eviction = %v", got)
+	}
+}
+
+func TestCache_SetRejectsOlderVersion(t *testing.T) {
+	cache := New()
+	cache.Set(&models.Order{OrderUID: "order-1", Version: 2, TrackNumber: "v2", ContentHash: "h2"})
+
+	if cache.Set(&models.Order{OrderUID: "order-1", Version: 1, TrackNumber: "v1"}) {
+		t.Error("Older version should not be written")
+	}
+	if order, _ := cache.Get("order-1"); order.TrackNumber != "v2" {
+		t.Errorf("Expected v2 to remain, got %s", order.TrackNumber)
+	}
+
+	// Та же версия записывается только с тем же содержимым, заказ без версии поверх версии - нет
+	if !cache.Set(&models.Order{OrderUID: "order-1", Version: 2, TrackNumber: "v2", ContentHash: "h2"}) {
+		t.Error("Same version with the same content should be written")
+	}
+	if cache.Set(&models.Order{OrderUID: "order-1", Version: 2, TrackNumber: "v2b", ContentHash: "h2b"}) {
+		t.Error("Same version with different content should not be written")
+	}
+	if cache.Set(&models.Order{OrderUID: "order-1", TrackNumber: "unversioned"}) {
+		t.Error("Unversioned order should not replace a versioned one")
+	}
+}
+
+func TestCache_OnSet(t *testing.T) {
+	cache := New()
+	var notified []string
+	cache.OnSet(func(order *models.Order) { notified = append(notified, order.TrackNumber) })
+
+	cache.Restore([]*models.Order{{OrderUID: "order-1", Version: 2, TrackNumber: "restored"}})
+	cache.Set(&models.Order{OrderUID: "order-1", Version: 1, TrackNumber: "v1"})
+	cache.Set(&models.Order{OrderUID: "order-1", Version: 3, TrackNumber: "v3"})
+
+	if len(notified) != 1 || notified[0] != "v3" {
+		t.Errorf("Expected only the accepted write to be notified, got %v", notified)
+	}
+}
@@ -1,54 +1,251 @@
 package config
 
 import (
-    "fmt"
-    "gopkg.in/yaml.v3"
-    "os"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+const DefaultPath = "config.yaml"
+
+// Поля с тегом env переопределяются переменными окружения, а каждому полю
+// соответствует флаг с путем из yaml-тегов (например, -database.host).
+// Поля с тегом secret маскируются при печати конфигурации, а поля с тегом
+// reload:"safe" можно менять без перезапуска (см. Store).
 type Config struct {
-    HTTP struct {
-        Address string `yaml:"address"`
-    } `yaml:"http"`
-    Database struct {
-        Host     string `yaml:"host"`
-        Port     int    `yaml:"port"`
-        User     string `yaml:"user"`
-        Password string `yaml:"password"`
-        DBName   string `yaml:"dbname"`
-        SSLMode  string `yaml:"sslmode"`
-    } `yaml:"database"`
-    NATS struct {
-        URL      string `yaml:"url"`
-        ClusterID string `yaml:"cluster_id"`
-        ClientID  string `yaml:"client_id"`
-        Subject   string `yaml:"subject"`
-    } `yaml:"nats"`
-}
-
-func Load() *Config {
-    var cfg Config
-    
-    data, err := os.ReadFile("config.yaml")
-    if err != nil {
-        // Значения по умолчанию
-        cfg.HTTP.Address = ":8080"
-        cfg.Database.Host = "localhost"
-        cfg.Database.Port = 5432
-        cfg.Database.User = "order_user"
-        cfg.Database.Password = "order_password"
-        cfg.Database.DBName = "orders"
-        cfg.Database.SSLMode = "disable"
-        cfg.NATS.URL = "nats://localhost:4222"
-        cfg.NATS.ClusterID = "test-cluster"
-        cfg.NATS.ClientID = "order-service"
-        cfg.NATS.Subject = "orders"
-        return &cfg
-    }
-    
-    if err := yaml.Unmarshal(data, &cfg); err != nil {
-        panic(fmt.Sprintf("Error parsing config: %v", err))
-    }
-    
-    return &cfg
-}
\ No newline at end of file
+	HTTP struct {
+		Address   string  `yaml:"address" env:"HTTP_ADDRESS"`
+		RateLimit float64 `yaml:"rate_limit" env:"HTTP_RATE_LIMIT" reload:"safe"`
+		RateBurst int     `yaml:"rate_burst" env:"HTTP_RATE_BURST" reload:"safe"`
+	} `yaml:"http"`
+	GRPC struct {
+		// Пустой адрес отключает gRPC-сервер
+		Address string `yaml:"address" env:"GRPC_ADDRESS"`
+	} `yaml:"grpc"`
+	Admin struct {
+		// Bearer-токен для /admin/*; пустой токен отключает эти маршруты
+		Token string `yaml:"token" env:"ADMIN_TOKEN" secret:"true" reload:"safe"`
+	} `yaml:"admin"`
+	Database struct {
+		// DSN (postgres://... или key=value) заменяет host, port, user, password, dbname и sslmode
+		DSN          string `yaml:"dsn" env:"DATABASE_DSN" secret:"true"`
+		Host         string `yaml:"host" env:"DATABASE_HOST"`
+		Port         int    `yaml:"port" env:"DATABASE_PORT"`
+		User         string `yaml:"user" env:"DATABASE_USER"`
+		Password     string `yaml:"password" env:"DATABASE_PASSWORD" secret:"true"`
+		PasswordFile string `yaml:"password_file" env:"DATABASE_PASSWORD_FILE"`
+		DBName       string `yaml:"dbname" env:"DATABASE_NAME"`
+		SSLMode      string `yaml:"sslmode" env:"DATABASE_SSLMODE"`
+		SSLRootCert  string `yaml:"sslrootcert" env:"DATABASE_SSLROOTCERT"`
+		SSLCert      string `yaml:"sslcert" env:"DATABASE_SSLCERT"`
+		SSLKey       string `yaml:"sslkey" env:"DATABASE_SSLKEY"`
+
+		MaxOpenConns    int           `yaml:"max_open_conns" env:"DATABASE_MAX_OPEN_CONNS"`
+		MaxIdleConns    int           `yaml:"max_idle_conns" env:"DATABASE_MAX_IDLE_CONNS"`
+		ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DATABASE_CONN_MAX_LIFETIME"`
+		ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DATABASE_CONN_MAX_IDLE_TIME"`
+	} `yaml:"database"`
+	NATS struct {
+		// stan - NATS Streaming (устаревший), jetstream - NATS JetStream, none - без брокера
+		Broker    string `yaml:"broker" env:"NATS_BROKER"`
+		URL       string `yaml:"url" env:"NATS_URL"`
+		ClusterID string `yaml:"cluster_id" env:"NATS_CLUSTER_ID"`
+		ClientID  string `yaml:"client_id" env:"NATS_CLIENT_ID"`
+		Subject   string `yaml:"subject" env:"NATS_SUBJECT"`
+		// Параллельная обработка: 0 - последовательно в callback подписки
+		Workers   int           `yaml:"workers" env:"NATS_WORKERS"`
+		QueueSize int           `yaml:"queue_size" env:"NATS_QUEUE_SIZE"`
+		AckWait   time.Duration `yaml:"ack_wait" env:"NATS_ACK_WAIT"`
+		// Только для JetStream
+		Stream     string `yaml:"stream" env:"NATS_STREAM"`
+		MaxDeliver int    `yaml:"max_deliver" env:"NATS_MAX_DELIVER"`
+	} `yaml:"nats"`
+	Events struct {
+		// Доменные события о заказах публикуются в тот же брокер, что и nats.broker
+		Enabled              bool   `yaml:"enabled" env:"EVENTS_ENABLED"`
+		CreatedSubject       string `yaml:"created_subject" env:"EVENTS_CREATED_SUBJECT"`
+		UpdatedSubject       string `yaml:"updated_subject" env:"EVENTS_UPDATED_SUBJECT"`
+		StatusChangedSubject string `yaml:"status_changed_subject" env:"EVENTS_STATUS_CHANGED_SUBJECT"`
+		RejectedSubject      string `yaml:"rejected_subject" env:"EVENTS_REJECTED_SUBJECT"`
+		// Поток JetStream, который хранит события (только для nats.broker: jetstream)
+		Stream string `yaml:"stream" env:"EVENTS_STREAM"`
+		// События пишутся в таблицу outbox и публикуются фоновым relay
+		RelayInterval   time.Duration `yaml:"relay_interval" env:"EVENTS_RELAY_INTERVAL"`
+		BatchSize       int           `yaml:"batch_size" env:"EVENTS_BATCH_SIZE"`
+		Retention       time.Duration `yaml:"retention" env:"EVENTS_RETENTION"`
+		CleanupInterval time.Duration `yaml:"cleanup_interval" env:"EVENTS_CLEANUP_INTERVAL"`
+	} `yaml:"events"`
+	Webhooks struct {
+		// HTTP-доставка событий партнерам; требует events.enabled
+		Enabled     bool          `yaml:"enabled" env:"WEBHOOKS_ENABLED"`
+		Interval    time.Duration `yaml:"interval" env:"WEBHOOKS_INTERVAL"`
+		Timeout     time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT"`
+		MaxAttempts int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS"`
+		Backoff     time.Duration `yaml:"backoff" env:"WEBHOOKS_BACKOFF"`
+		MaxBackoff  time.Duration `yaml:"max_backoff" env:"WEBHOOKS_MAX_BACKOFF"`
+		// Одновременных запросов к одному получателю; разные получатели обслуживаются параллельно
+		Concurrency int `yaml:"concurrency" env:"WEBHOOKS_CONCURRENCY"`
+	} `yaml:"webhooks"`
+	Tracing struct {
+		Exporter    string `yaml:"exporter" env:"TRACING_EXPORTER"`
+		Endpoint    string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+		ServiceName string `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+	} `yaml:"tracing"`
+	Logging struct {
+		Level            string `yaml:"level" env:"LOG_LEVEL" reload:"safe"`
+		DisableRedaction bool   `yaml:"disable_redaction" env:"LOG_DISABLE_REDACTION"`
+	} `yaml:"logging"`
+	Stream struct {
+		// Сколько последних изменений хранится для возобновления /orders/stream по Last-Event-ID
+		BufferSize int `yaml:"buffer_size" env:"STREAM_BUFFER_SIZE"`
+	} `yaml:"stream"`
+	Cache struct {
+		MaxOrders int `yaml:"max_orders" env:"CACHE_MAX_ORDERS" reload:"safe"`
+	} `yaml:"cache"`
+	Validation struct {
+		Strict bool `yaml:"strict" env:"VALIDATION_STRICT" reload:"safe"`
+	} `yaml:"validation"`
+	Decoding struct {
+		// Строгий разбор без приведения типов
+		Strict bool `yaml:"strict" env:"DECODING_STRICT" reload:"safe"`
+	} `yaml:"decoding"`
+
+	path string
+}
+
+// Значения по умолчанию
+func Default() *Config {
+	var cfg Config
+	cfg.HTTP.Address = ":8080"
+	cfg.GRPC.Address = ":9090"
+	cfg.Database.Host = "localhost"
+	cfg.Database.Port = 5432
+	cfg.Database.User = "order_user"
+	cfg.Database.Password = "order_password"
+	cfg.Database.DBName = "orders"
+	cfg.Database.SSLMode = "disable"
+	cfg.Database.MaxOpenConns = 25
+	cfg.Database.MaxIdleConns = 25
+	cfg.Database.ConnMaxLifetime = 30 * time.Minute
+	cfg.NATS.Broker = "stan"
+	cfg.NATS.URL = "nats://localhost:4222"
+	cfg.NATS.ClusterID = "test-cluster"
+	cfg.NATS.ClientID = "order-service"
+	cfg.NATS.Subject = "orders"
+	cfg.NATS.Workers = 4
+	cfg.NATS.QueueSize = 64
+	cfg.NATS.AckWait = 30 * time.Second
+	cfg.NATS.Stream = "ORDERS"
+	cfg.NATS.MaxDeliver = 5
+	cfg.Events.Enabled = true
+	cfg.Events.CreatedSubject = "orders.events.created"
+	cfg.Events.UpdatedSubject = "orders.events.updated"
+	cfg.Events.StatusChangedSubject = "orders.events.status_changed"
+	cfg.Events.RejectedSubject = "orders.events.rejected"
+	cfg.Events.Stream = "ORDER_EVENTS"
+	cfg.Events.RelayInterval = time.Second
+	cfg.Events.BatchSize = 100
+	cfg.Events.Retention = 7 * 24 * time.Hour
+	cfg.Events.CleanupInterval = time.Hour
+	cfg.Webhooks.Interval = time.Second
+	cfg.Webhooks.Timeout = 10 * time.Second
+	cfg.Webhooks.MaxAttempts = 8
+	cfg.Webhooks.Backoff = 5 * time.Second
+	cfg.Webhooks.MaxBackoff = 30 * time.Minute
+	cfg.Webhooks.Concurrency = 1
+	cfg.Stream.BufferSize = 1000
+	cfg.Tracing.Exporter = "none"
+	cfg.Tracing.ServiceName = "order-service"
+	cfg.Logging.Level = "info"
+	return &cfg
+}
+
+// Load собирает конфигурацию по слоям: значения по умолчанию, файл
+// (путь из флага -config, CONFIG_PATH или config.yaml), переменные окружения, флаги.
+// Итоговая конфигурация проверяется через Validate.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("order-service", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	loader := NewLoader(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("invalid flags: %v", err)
+	}
+	return loader.Load()
+}
+
+// Loader позволяет программам объявить собственные флаги рядом с флагами
+// конфигурации: NewLoader регистрирует их в fs, а Load вызывается после fs.Parse.
+type Loader struct {
+	fs        *flag.FlagSet
+	cfg       *Config
+	path      *string
+	overrides flagOverrides
+}
+
+func NewLoader(fs *flag.FlagSet) *Loader {
+	cfg := Default()
+	return &Loader{
+		fs:        fs,
+		cfg:       cfg,
+		path:      fs.String("config", "", "path to config file (default $CONFIG_PATH or "+DefaultPath+")"),
+		overrides: registerFlags(fs, cfg),
+	}
+}
+
+func (l *Loader) Load() (*Config, error) {
+	cfg := l.cfg
+
+	path, explicit := *l.path, true
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		path, explicit = DefaultPath, false
+	}
+
+	if err := loadFile(cfg, path, explicit); err != nil {
+		return nil, err
+	}
+	cfg.path = path
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+	if err := l.overrides.apply(l.fs); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Path - путь к файлу, из которого загружена конфигурация.
+func (c *Config) Path() string {
+	return c.path
+}
+
+func loadFile(cfg *Config, path string, explicit bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Файл по умолчанию необязателен: конфигурация может прийти целиком из окружения
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Layers(t *testing.T) {
+	path := writeConfig(t, `
+database:
+  host: "file-host"
+  user: "file-user"
+nats:
+  subject: "file-subject"
+`)
+	t.Setenv("CONFIG_PATH", path)
+	t.Setenv("DATABASE_USER", "env-user")
+	t.Setenv("NATS_SUBJECT", "env-subject")
+
+	cfg, err := Load([]string{"-nats.subject", "flag-subject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Значение по умолчанию сохраняется, если его нет в файле
+	if cfg.HTTP.Address != ":8080" {
+		t.Errorf("Expected default http.address, got %q", cfg.HTTP.Address)
+	}
+	if cfg.Database.Host != "file-host" {
+		t.Errorf("Expected database.host from file, got %q", cfg.Database.Host)
+	}
+	if cfg.Database.User != "env-user" {
+		t.Errorf("Expected database.user from env, got %q", cfg.Database.User)
+	}
+	if cfg.NATS.Subject != "flag-subject" {
+		t.Errorf("Expected nats.subject from flag, got %q", cfg.NATS.Subject)
+	}
+}
+
+func TestLoad_ConfigFlagOverridesEnvPath(t *testing.T) {
+	t.Setenv("CONFIG_PATH", filepath.Join(t.TempDir(), "missing.yaml"))
+	path := writeConfig(t, "http:\n  address: \":9090\"\n")
+
+	cfg, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTP.Address != ":9090" {
+		t.Errorf("Expected :9090, got %q", cfg.HTTP.Address)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "Malformed YAML", file: "http: [", wantErr: "failed to parse config file"},
+		{name: "Unknown field", file: "database:\n  hots: x\n", wantErr: "hots"},
+		{name: "Invalid port in env", env: map[string]string{"DATABASE_PORT": "abc"}, wantErr: "DATABASE_PORT"},
+		{name: "Out of range port", env: map[string]string{"DATABASE_PORT": "70000"}, wantErr: "database.port"},
+		{name: "Unknown sslmode", env: map[string]string{"DATABASE_SSLMODE": "maybe"}, wantErr: "database.sslmode"},
+		{name: "Event subject loops back", env: map[string]string{"EVENTS_REJECTED_SUBJECT": "orders"}, wantErr: "events.rejected_subject"},
+		{name: "Event stream shared with orders", env: map[string]string{"NATS_BROKER": "jetstream", "EVENTS_STREAM": "ORDERS"}, wantErr: "events.stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_PATH", writeConfig(t, tt.file))
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load(nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_MissingExplicitFile(t *testing.T) {
+	if _, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("Expected error for missing explicit config file")
+	}
+}
+
+func TestConfig_PrintMasksSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "super secret"
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "super secret") {
+		t.Errorf("Password leaked in output: %s", buf.String())
+	}
+	if cfg.Database.Password != "super secret" {
+		t.Error("Print must not modify the config")
+	}
+}
+
+func TestStore_ReloadAppliesSafeAndRejectsUnsafe(t *testing.T) {
+	path := writeConfig(t, "logging:\n  level: info\n")
+	args := []string{"-config", path}
+
+	cfg, err := Load(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(cfg, args)
+
+	var notified *Config
+	store.OnReload(func(c *Config) { notified = c })
+
+	if err := os.WriteFile(path, []byte("logging:\n  level: debug\ndatabase:\n  host: other-host\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, rejected, err := store.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].Path != "logging.level" {
+		t.Errorf("Expected logging.level to be applied, got %v", applied)
+	}
+	if len(rejected) != 1 || rejected[0].Path != "database.host" {
+		t.Errorf("Expected database.host to be rejected, got %v", rejected)
+	}
+
+	current := store.Current()
+	if current.Version != 2 {
+		t.Errorf("Expected version 2, got %d", current.Version)
+	}
+	if current.Config.Logging.Level != "debug" {
+		t.Errorf("Expected level debug, got %q", current.Config.Logging.Level)
+	}
+	if current.Config.Database.Host != "localhost" {
+		t.Errorf("Unsafe change must not be applied, got host %q", current.Config.Database.Host)
+	}
+	if notified != current.Config {
+		t.Error("Listeners should receive the new config")
+	}
+}
+
+func TestStore_ReloadInvalidKeepsCurrent(t *testing.T) {
+	path := writeConfig(t, "")
+	args := []string{"-config", path}
+
+	cfg, err := Load(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(cfg, args)
+
+	if err := os.WriteFile(path, []byte("logging:\n  level: loud\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := store.Reload(); err == nil {
+		t.Error("Expected validation error")
+	}
+	if store.Current().Version != 1 {
+		t.Error("Invalid config must not be applied")
+	}
+}
+
+func TestLoad_DatabaseDSNAndPool(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(secret, []byte("s3cret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_PATH", writeConfig(t, `
+database:
+  dsn: "postgres://user@db:5432/orders"
+  host: ""
+  password_file: "`+secret+`"
+  conn_max_lifetime: "5m"
+`))
+	t.Setenv("DATABASE_CONN_MAX_IDLE_TIME", "90s")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.ConnMaxLifetime.String() != "5m0s" {
+		t.Errorf("Expected conn_max_lifetime 5m, got %v", cfg.Database.ConnMaxLifetime)
+	}
+	if cfg.Database.ConnMaxIdleTime.String() != "1m30s" {
+		t.Errorf("Expected conn_max_idle_time 90s, got %v", cfg.Database.ConnMaxIdleTime)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "postgres://") {
+		t.Errorf("DSN must be masked: %s", buf.String())
+	}
+}
+
+func TestLoad_MissingPasswordFile(t *testing.T) {
+	t.Setenv("CONFIG_PATH", writeConfig(t, ""))
+	t.Setenv("DATABASE_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, err := Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "database.password_file") {
+		t.Errorf("Expected password_file error, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field - лист конфигурации с yaml-путем вида "database.host".
+type field struct {
+	path   string
+	env    string
+	secret bool
+	safe   bool
+	value  reflect.Value
+}
+
+func fields(cfg *Config) []field {
+	var out []field
+	walk(reflect.ValueOf(cfg).Elem(), "", &out)
+	return out
+}
+
+func walk(v reflect.Value, prefix string, out *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" || !sf.IsExported() {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		if sf.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), path, out)
+			continue
+		}
+
+		*out = append(*out, field{
+			path:   path,
+			env:    sf.Tag.Get("env"),
+			secret: sf.Tag.Get("secret") == "true",
+			safe:   sf.Tag.Get("reload") == "safe",
+			value:  v.Field(i),
+		})
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (f field) set(raw string) error {
+	if f.value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a duration", f.path, raw)
+		}
+		f.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", f.path, raw)
+		}
+		f.value.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", f.path, raw)
+		}
+		f.value.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a boolean", f.path, raw)
+		}
+		f.value.SetBool(b)
+	default:
+		return fmt.Errorf("%s: unsupported type %s", f.path, f.value.Type())
+	}
+	return nil
+}
+
+func applyEnv(cfg *Config) error {
+	for _, f := range fields(cfg) {
+		if f.env == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(f.env)
+		if !ok {
+			continue
+		}
+		if err := f.set(raw); err != nil {
+			return fmt.Errorf("invalid environment variable %s: %v", f.env, err)
+		}
+	}
+	return nil
+}
+
+type flagOverride struct {
+	field field
+	raw   *string
+}
+
+type flagOverrides map[string]flagOverride
+
+// registerFlags объявляет по строковому флагу на каждое поле; применяются только
+// явно переданные флаги, поэтому они перекрывают файл и окружение.
+func registerFlags(fs *flag.FlagSet, cfg *Config) flagOverrides {
+	overrides := flagOverrides{}
+	for _, f := range fields(cfg) {
+		overrides[f.path] = flagOverride{
+			field: f,
+			raw:   fs.String(f.path, "", fmt.Sprintf("override %s", f.path)),
+		}
+	}
+	return overrides
+}
+
+func (o flagOverrides) apply(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		override, ok := o[fl.Name]
+		if !ok || err != nil {
+			return
+		}
+		if setErr := override.field.set(*override.raw); setErr != nil {
+			err = fmt.Errorf("invalid flag -%s: %v", fl.Name, setErr)
+		}
+	})
+	return err
+}
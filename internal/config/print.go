@@ -0,0 +1,58 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const secretMask = "********"
+
+// Print выводит действующую конфигурацию в YAML, маскируя секреты.
+func (c *Config) Print(w io.Writer) error {
+	masked := *c
+	for _, f := range fields(&masked) {
+		if f.secret && f.value.Kind() == reflect.String && f.value.String() != "" {
+			f.value.SetString(secretMask)
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&masked); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Masked возвращает конфигурацию в виде вложенных map с замаскированными секретами.
+func (c *Config) Masked() map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, f := range fields(c) {
+		section := out
+		parts := strings.Split(f.path, ".")
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := section[p].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				section[p] = next
+			}
+			section = next
+		}
+		section[parts[len(parts)-1]] = f.display()
+	}
+	return out
+}
+
+func (f field) display() interface{} {
+	if f.secret && f.value.Kind() == reflect.String && f.value.String() != "" {
+		return secretMask
+	}
+	if d, ok := f.value.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return f.value.Interface()
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Snapshot - активная версия конфигурации.
+type Snapshot struct {
+	Config   *Config
+	Version  int
+	LoadedAt time.Time
+}
+
+// Change - отличие одного поля между двумя версиями конфигурации.
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+	Safe bool        `json:"safe"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff сравнивает две конфигурации поле за полем; секреты в значениях маскируются.
+func Diff(old, new *Config) []Change {
+	oldFields := fields(old)
+	newFields := fields(new)
+
+	var changes []Change
+	for i, of := range oldFields {
+		nf := newFields[i]
+		if reflect.DeepEqual(of.value.Interface(), nf.value.Interface()) {
+			continue
+		}
+		changes = append(changes, Change{
+			Path: of.path,
+			Old:  of.display(),
+			New:  nf.display(),
+			Safe: of.safe,
+		})
+	}
+	return changes
+}
+
+// Store хранит активную конфигурацию и перечитывает ее по SIGHUP или при
+// изменении файла. Применяются только поля с тегом reload:"safe"; остальные
+// изменения (DSN базы, кластер NATS и т.п.) отклоняются до перезапуска.
+type Store struct {
+	args      []string
+	current   atomic.Pointer[Snapshot]
+	mu        sync.Mutex
+	listeners []func(*Config)
+}
+
+func NewStore(cfg *Config, args []string) *Store {
+	s := &Store{args: args}
+	s.current.Store(&Snapshot{Config: cfg, Version: 1, LoadedAt: time.Now()})
+	return s
+}
+
+func (s *Store) Current() *Snapshot {
+	return s.current.Load()
+}
+
+// OnReload регистрирует функцию, которая применяет новую конфигурацию к компоненту.
+func (s *Store) OnReload(fn func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Reload перечитывает конфигурацию теми же слоями, что и Load. Безопасные
+// изменения применяются, небезопасные возвращаются в rejected без применения.
+func (s *Store) Reload() (applied, rejected []Change, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loaded, err := Load(s.args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prev := s.current.Load()
+	next := *prev.Config
+	nextFields := fields(&next)
+	loadedFields := fields(loaded)
+
+	for _, change := range Diff(prev.Config, loaded) {
+		if !change.Safe {
+			rejected = append(rejected, change)
+			continue
+		}
+		for i, f := range nextFields {
+			if f.path == change.Path {
+				f.value.Set(loadedFields[i].value)
+			}
+		}
+		applied = append(applied, change)
+	}
+
+	if len(applied) == 0 {
+		return nil, rejected, nil
+	}
+
+	s.current.Store(&Snapshot{Config: &next, Version: prev.Version + 1, LoadedAt: time.Now()})
+	for _, fn := range s.listeners {
+		fn(&next)
+	}
+	return applied, rejected, nil
+}
+
+// Watch перечитывает конфигурацию по SIGHUP и при изменении времени
+// модификации файла (проверяется каждые interval). Блокируется до отмены ctx.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	path := s.Current().Config.Path()
+	lastMod := modTime(path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("SIGHUP received, reloading config")
+			s.reloadAndLog()
+		case <-ticker.C:
+			if mod := modTime(path); !mod.Equal(lastMod) {
+				lastMod = mod
+				slog.Info("Config file changed, reloading", "path", path)
+				s.reloadAndLog()
+			}
+		}
+	}
+}
+
+func (s *Store) reloadAndLog() {
+	applied, rejected, err := s.Reload()
+	if err != nil {
+		slog.Error("Config reload failed, keeping current config", "error", err)
+		return
+	}
+	if len(rejected) > 0 {
+		slog.Warn("Config changes require restart and were rejected", "diff", changeStrings(rejected))
+	}
+	if len(applied) > 0 {
+		slog.Info("Config reloaded", "version", s.Current().Version, "diff", changeStrings(applied))
+	}
+}
+
+func changeStrings(changes []Change) string {
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var (
+	sslModes  = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	exporters = []string{"none", "stdout", "otlp"}
+	brokers   = []string{"stan", "jetstream", "none"}
+)
+
+// Validate возвращает все найденные ошибки конфигурации сразу.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.HTTP.Address == "" {
+		fail("http.address is required")
+	}
+	if c.HTTP.RateLimit < 0 {
+		fail("http.rate_limit must not be negative, got %v", c.HTTP.RateLimit)
+	}
+	if c.HTTP.RateBurst < 0 {
+		fail("http.rate_burst must not be negative, got %d", c.HTTP.RateBurst)
+	}
+
+	// При заданном DSN отдельные поля подключения не используются
+	if c.Database.DSN == "" {
+		if c.Database.Host == "" {
+			fail("database.host is required when database.dsn is not set")
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			fail("database.port must be between 1 and 65535, got %d", c.Database.Port)
+		}
+		if c.Database.User == "" {
+			fail("database.user is required when database.dsn is not set")
+		}
+		if c.Database.DBName == "" {
+			fail("database.dbname is required when database.dsn is not set")
+		}
+		if !oneOf(c.Database.SSLMode, sslModes) {
+			fail("database.sslmode must be one of %s, got %q", strings.Join(sslModes, ", "), c.Database.SSLMode)
+		}
+	} else if strings.Contains(c.Database.DSN, "://") {
+		if u, err := url.Parse(c.Database.DSN); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			fail("database.dsn must be a postgres:// URL or a key=value connection string")
+		}
+	}
+	for _, file := range []struct{ name, path string }{
+		{"database.password_file", c.Database.PasswordFile},
+		{"database.sslrootcert", c.Database.SSLRootCert},
+		{"database.sslcert", c.Database.SSLCert},
+		{"database.sslkey", c.Database.SSLKey},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			fail("%s: %v", file.name, err)
+		}
+	}
+	if (c.Database.SSLCert == "") != (c.Database.SSLKey == "") {
+		fail("database.sslcert and database.sslkey must be set together")
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		fail("database pool sizes must not be negative")
+	}
+	if c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 {
+		fail("database connection lifetimes must not be negative")
+	}
+
+	if u, err := url.Parse(c.NATS.URL); c.NATS.Broker != "none" && (c.NATS.URL == "" || err != nil || u.Host == "") {
+		fail("nats.url must be a URL like nats://host:4222, got %q", c.NATS.URL)
+	}
+	if !oneOf(c.NATS.Broker, brokers) {
+		fail("nats.broker must be one of %s, got %q", strings.Join(brokers, ", "), c.NATS.Broker)
+	}
+	if c.NATS.Broker == "stan" && c.NATS.ClusterID == "" {
+		fail("nats.cluster_id is required for the stan broker")
+	}
+	if c.NATS.Broker == "jetstream" {
+		if c.NATS.Stream == "" {
+			fail("nats.stream is required for the jetstream broker")
+		}
+		if c.NATS.MaxDeliver == 0 || c.NATS.MaxDeliver < -1 {
+			fail("nats.max_deliver must be positive or -1 for unlimited, got %d", c.NATS.MaxDeliver)
+		}
+	}
+	if c.NATS.ClientID == "" {
+		fail("nats.client_id is required")
+	}
+	if c.NATS.Subject == "" {
+		fail("nats.subject is required")
+	}
+	if c.NATS.Workers < 0 {
+		fail("nats.workers must not be negative, got %d", c.NATS.Workers)
+	}
+	if c.NATS.Workers > 0 && c.NATS.QueueSize < 1 {
+		fail("nats.queue_size must be at least 1 when nats.workers is set, got %d", c.NATS.QueueSize)
+	}
+	if c.NATS.AckWait < 0 {
+		fail("nats.ack_wait must not be negative, got %s", c.NATS.AckWait)
+	}
+
+	if c.Events.Enabled {
+		for _, subject := range []struct{ name, value string }{
+			{"events.created_subject", c.Events.CreatedSubject},
+			{"events.updated_subject", c.Events.UpdatedSubject},
+			{"events.status_changed_subject", c.Events.StatusChangedSubject},
+			{"events.rejected_subject", c.Events.RejectedSubject},
+		} {
+			switch subject.value {
+			case "":
+				fail("%s is required when events are enabled", subject.name)
+			case c.NATS.Subject:
+				// Иначе сервис будет читать собственные события как заказы
+				fail("%s must differ from nats.subject", subject.name)
+			}
+		}
+		if c.NATS.Broker == "jetstream" {
+			switch c.Events.Stream {
+			case "":
+				fail("events.stream is required for the jetstream broker")
+			case c.NATS.Stream:
+				fail("events.stream must differ from nats.stream")
+			}
+		}
+		if c.Events.RelayInterval <= 0 {
+			fail("events.relay_interval must be positive, got %s", c.Events.RelayInterval)
+		}
+		if c.Events.BatchSize < 1 {
+			fail("events.batch_size must be at least 1, got %d", c.Events.BatchSize)
+		}
+		// retention = 0 - отправленные события не удаляются
+		if c.Events.Retention < 0 {
+			fail("events.retention must not be negative, got %s", c.Events.Retention)
+		}
+		if c.Events.Retention > 0 && c.Events.CleanupInterval <= 0 {
+			fail("events.cleanup_interval must be positive when events.retention is set, got %s", c.Events.CleanupInterval)
+		}
+	}
+
+	if c.Webhooks.Enabled {
+		if !c.Events.Enabled {
+			fail("webhooks.enabled requires events.enabled")
+		}
+		if c.Webhooks.Interval <= 0 || c.Webhooks.Timeout <= 0 {
+			fail("webhooks.interval and webhooks.timeout must be positive")
+		}
+		if c.Webhooks.MaxAttempts < 1 {
+			fail("webhooks.max_attempts must be at least 1, got %d", c.Webhooks.MaxAttempts)
+		}
+		if c.Webhooks.Backoff <= 0 || c.Webhooks.MaxBackoff < c.Webhooks.Backoff {
+			fail("webhooks.backoff must be positive and not greater than webhooks.max_backoff")
+		}
+		if c.Webhooks.Concurrency < 1 {
+			fail("webhooks.concurrency must be at least 1, got %d", c.Webhooks.Concurrency)
+		}
+	}
+
+	if !oneOf(c.Tracing.Exporter, exporters) {
+		fail("tracing.exporter must be one of %s, got %q", strings.Join(exporters, ", "), c.Tracing.Exporter)
+	}
+	if c.Tracing.Exporter == "otlp" && c.Tracing.Endpoint == "" {
+		fail("tracing.endpoint is required for the otlp exporter")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Logging.Level)); err != nil {
+		fail("logging.level must be one of debug, info, warn, error, got %q", c.Logging.Level)
+	}
+
+	if c.Stream.BufferSize < 1 {
+		fail("stream.buffer_size must be at least 1, got %d", c.Stream.BufferSize)
+	}
+
+	if c.Cache.MaxOrders < 0 {
+		fail("cache.max_orders must not be negative, got %d", c.Cache.MaxOrders)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
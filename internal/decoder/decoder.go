@@ -0,0 +1,333 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"order-service/internal/models"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Форматы дат, которые встречаются у продюсеров помимо RFC3339.
+var timeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02",
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	itemStatusType  = reflect.TypeOf(models.ItemStatus(""))
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// Decode разбирает заказ. В строгом режиме это обычный json.Unmarshal; в мягком
+// известные расхождения типов (числа и строки, RFC3339 и epoch, null) приводятся
+// к типам models.Order, а каждое приведение записывается в order.Warnings.
+func Decode(data []byte, strict bool) (*models.Order, error) {
+	var order models.Order
+	if strict {
+		if err := json.Unmarshal(data, &order); err != nil {
+			return nil, err
+		}
+		// ItemStatus сам понимает устаревшие коды, поэтому их отклоняем отдельно
+		if err := rejectItemStatusCodes(data); err != nil {
+			return nil, err
+		}
+		return &order, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	c := &coercer{}
+	normalized, err := c.coerce("", raw, reflect.TypeOf(order))
+	if err != nil {
+		return nil, err
+	}
+
+	fixed, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(fixed, &order); err != nil {
+		return nil, err
+	}
+
+	order.Warnings = c.warnings
+	return &order, nil
+}
+
+type coercer struct {
+	warnings []models.Warning
+}
+
+func (c *coercer) warn(path, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, models.Warning{Field: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *coercer) coerce(path string, v interface{}, t reflect.Type) (interface{}, error) {
+	if t == timeType {
+		return c.coerceTime(path, v)
+	}
+	if t == itemStatusType {
+		return c.coerceItemStatus(path, v)
+	}
+	// Типы со своим UnmarshalJSON (например, ItemStatus) разбирают значение сами
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return v, nil
+	}
+
+	if v == nil {
+		switch t.Kind() {
+		case reflect.String, reflect.Int, reflect.Int64, reflect.Float64, reflect.Bool:
+			c.warn(path, "null replaced with zero value")
+		}
+		return nil, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return c.coerceStruct(path, v, t)
+	case reflect.Slice:
+		return c.coerceSlice(path, v, t)
+	case reflect.String:
+		return c.coerceString(path, v)
+	case reflect.Int, reflect.Int64:
+		return c.coerceInt(path, v)
+	case reflect.Float64:
+		return c.coerceFloat(path, v)
+	}
+	return v, nil
+}
+
+func (c *coercer) coerceStruct(path string, v interface{}, t reflect.Type) (interface{}, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected object, got %s", displayPath(path), jsonKind(v))
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		value, present := obj[name]
+		if !present {
+			continue
+		}
+		coerced, err := c.coerce(joinPath(path, name), value, sf.Type)
+		if err != nil {
+			return nil, err
+		}
+		obj[name] = coerced
+	}
+	return obj, nil
+}
+
+func (c *coercer) coerceSlice(path string, v interface{}, t reflect.Type) (interface{}, error) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		// Одиночный объект вместо массива
+		c.warn(path, "%s wrapped into array", jsonKind(v))
+		arr = []interface{}{v}
+	}
+
+	for i, el := range arr {
+		coerced, err := c.coerce(fmt.Sprintf("%s[%d]", path, i), el, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = coerced
+	}
+	return arr, nil
+}
+
+func (c *coercer) coerceString(path string, v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		c.warn(path, "number coerced to string")
+		return t.String(), nil
+	case bool:
+		c.warn(path, "boolean coerced to string")
+		return strconv.FormatBool(t), nil
+	}
+	return nil, fmt.Errorf("%s: expected string, got %s", displayPath(path), jsonKind(v))
+}
+
+func (c *coercer) coerceInt(path string, v interface{}) (interface{}, error) {
+	var n json.Number
+	switch t := v.(type) {
+	case json.Number:
+		n = t
+	case string:
+		c.warn(path, "string coerced to integer")
+		n = json.Number(strings.TrimSpace(t))
+	default:
+		return nil, fmt.Errorf("%s: expected integer, got %s", displayPath(path), jsonKind(v))
+	}
+
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %q is not a number", displayPath(path), n)
+	}
+	if f != math.Trunc(f) {
+		return nil, fmt.Errorf("%s: %v is not an integer", displayPath(path), f)
+	}
+	c.warn(path, "float coerced to integer")
+	return int64(f), nil
+}
+
+func (c *coercer) coerceFloat(path string, v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case json.Number:
+		return t, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a number", displayPath(path), t)
+		}
+		c.warn(path, "string coerced to number")
+		return f, nil
+	}
+	return nil, fmt.Errorf("%s: expected number, got %s", displayPath(path), jsonKind(v))
+}
+
+func (c *coercer) coerceTime(path string, v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case nil:
+		c.warn(path, "null replaced with zero time")
+		return nil, nil
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return t, nil
+		}
+		for _, layout := range timeLayouts {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				c.warn(path, "timestamp in layout %q coerced to RFC3339", layout)
+				return parsed.UTC().Format(time.RFC3339Nano), nil
+			}
+		}
+		// Epoch, переданный строкой
+		if n, err := strconv.ParseInt(t, 10, 64); err == nil {
+			c.warn(path, "epoch string coerced to RFC3339")
+			return epoch(n).Format(time.RFC3339Nano), nil
+		}
+		return nil, fmt.Errorf("%s: unrecognized timestamp format", displayPath(path))
+	case json.Number:
+		n, err := t.Int64()
+		if err != nil {
+			f, ferr := t.Float64()
+			if ferr != nil {
+				return nil, fmt.Errorf("%s: invalid epoch timestamp", displayPath(path))
+			}
+			n = int64(f)
+		}
+		c.warn(path, "epoch number coerced to RFC3339")
+		return epoch(n).Format(time.RFC3339Nano), nil
+	}
+	return nil, fmt.Errorf("%s: expected timestamp, got %s", displayPath(path), jsonKind(v))
+}
+
+// coerceItemStatus заменяет устаревший числовой код именем статуса.
+func (c *coercer) coerceItemStatus(path string, v interface{}) (interface{}, error) {
+	code, ok := itemStatusCode(v)
+	if !ok {
+		return v, nil
+	}
+	status, err := models.ItemStatusFromCode(code)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", displayPath(path), err)
+	}
+	c.warn(path, "status code %d coerced to %q", code, status)
+	return string(status), nil
+}
+
+// itemStatusCode распознает код статуса, переданный числом или строкой.
+func itemStatusCode(v interface{}) (int, bool) {
+	var raw string
+	switch t := v.(type) {
+	case json.Number:
+		raw = t.String()
+	case string:
+		raw = strings.TrimSpace(t)
+	default:
+		return 0, false
+	}
+	code, err := strconv.Atoi(raw)
+	return code, err == nil
+}
+
+func rejectItemStatusCodes(data []byte) error {
+	var probe struct {
+		Items []struct {
+			Status interface{} `json:"status"`
+		} `json:"items"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&probe); err != nil {
+		return err
+	}
+	for i, item := range probe.Items {
+		if _, ok := itemStatusCode(item.Status); ok {
+			return fmt.Errorf("items[%d].status: status codes are not accepted in strict mode", i)
+		}
+	}
+	return nil
+}
+
+// epoch понимает секунды и миллисекунды (значения больше 1e12 считаются миллисекундами).
+func epoch(n int64) time.Time {
+	if n > 1e12 || n < -1e12 {
+		return time.UnixMilli(n).UTC()
+	}
+	return time.Unix(n, 0).UTC()
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "order"
+	}
+	return path
+}
@@ -0,0 +1,129 @@
+package decoder
+
+import (
+	"order-service/internal/models"
+	"testing"
+	"time"
+)
+
+const driftedOrder = `{
+	"order_uid": "b563feb7b2b84b6test",
+	"track_number": "WBILMTESTTRACK",
+	"delivery": {"name": "Test Testov", "zip": 2639809, "email": null},
+	"payment": {"transaction": "b563feb7b2b84b6test", "amount": "1817", "payment_dt": 1637907727, "delivery_cost": 1500.0},
+	"items": {"chrt_id": 9934930, "status": 202, "price": 453, "rid": "ab4219087a764ae0btest"},
+	"sm_id": "99",
+	"date_created": 1637907739000,
+	"status": 1
+}`
+
+func TestDecode_Lenient(t *testing.T) {
+	order, err := Decode([]byte(driftedOrder), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Delivery.Zip != "2639809" {
+		t.Errorf("Expected zip as string, got %q", order.Delivery.Zip)
+	}
+	if order.Payment.Amount != 1817 || order.Payment.DeliveryCost != 1500 {
+		t.Errorf("Unexpected payment: %+v", order.Payment)
+	}
+	if order.SmID != 99 {
+		t.Errorf("Expected sm_id 99, got %d", order.SmID)
+	}
+	if want := time.UnixMilli(1637907739000).UTC(); !order.DateCreated.Equal(want) {
+		t.Errorf("Expected date_created %v, got %v", want, order.DateCreated)
+	}
+	if len(order.Items) != 1 || order.Items[0].Status != models.ItemProcessing {
+		t.Errorf("Unexpected items: %+v", order.Items)
+	}
+
+	fields := map[string]bool{}
+	for _, w := range order.Warnings {
+		fields[w.Field] = true
+	}
+	for _, want := range []string{"delivery.zip", "delivery.email", "payment.amount", "payment.delivery_cost", "items", "items[0].status", "sm_id", "date_created"} {
+		if !fields[want] {
+			t.Errorf("Expected warning for %s, got %+v", want, order.Warnings)
+		}
+	}
+}
+
+func TestDecode_CleanOrderHasNoWarnings(t *testing.T) {
+	order, err := Decode([]byte(`{"order_uid":"test-123","date_created":"2021-11-26T06:22:19Z","items":[{"status":"shipped"}]}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %+v", order.Warnings)
+	}
+}
+
+func TestDecode_TimestampLayouts(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{`"2021-11-26 06:22:19"`, time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)},
+		{`"2021-11-26"`, time.Date(2021, 11, 26, 0, 0, 0, 0, time.UTC)},
+		{`1637907739`, time.Unix(1637907739, 0).UTC()},
+		{`"1637907739"`, time.Unix(1637907739, 0).UTC()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			order, err := Decode([]byte(`{"date_created":`+tt.input+`}`), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !order.DateCreated.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, order.DateCreated)
+			}
+			if len(order.Warnings) != 1 {
+				t.Errorf("Expected 1 warning, got %+v", order.Warnings)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"Malformed JSON", `{"order_uid":`},
+		{"Fractional integer", `{"sm_id": 1.5}`},
+		{"Unknown item status code", `{"items": [{"status": 999}]}`},
+		{"Object instead of string", `{"order_uid": {"id": 1}}`},
+		{"Garbage timestamp", `{"date_created": "yesterday"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode([]byte(tt.data), false); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestDecode_Strict(t *testing.T) {
+	if _, err := Decode([]byte(driftedOrder), true); err == nil {
+		t.Error("Strict mode should reject type drift")
+	}
+
+	for _, status := range []string{`202`, `"202"`} {
+		if _, err := Decode([]byte(`{"order_uid":"test-123","items":[{"status":`+status+`}]}`), true); err == nil {
+			t.Errorf("Strict mode should reject item status code %s", status)
+		}
+	}
+
+	order, err := Decode([]byte(`{"order_uid":"test-123","items":[{"status":"shipped"}]}`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Warnings) != 0 {
+		t.Errorf("Strict mode must not record warnings, got %+v", order.Warnings)
+	}
+}
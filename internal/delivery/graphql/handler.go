@@ -0,0 +1,132 @@
+// Package graphql - /graphql: выборочное чтение заказов по схеме schema.graphql.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"order-service/internal/cache"
+	"order-service/internal/models"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// OrderStore - поиск и пакетная загрузка заказов (реализует repository.OrderRepository).
+type OrderStore interface {
+	GetOrders(ctx context.Context, uids []string) ([]*models.Order, error)
+	FindOrders(ctx context.Context, filter models.OrderFilter, after string, limit int) ([]string, error)
+	CountOrders(ctx context.Context, filter models.OrderFilter) (int, error)
+}
+
+type Handler struct {
+	schema *graphql.Schema
+	cache  *cache.Cache
+	store  OrderStore
+}
+
+func NewHandler(cache *cache.Cache, store OrderStore) *Handler {
+	schema := graphql.MustParseSchema(schemaSDL, &resolver{store: store},
+		graphql.MaxDepth(10),
+		graphql.MaxQueryLength(16<<10),
+	)
+	return &Handler{schema: schema, cache: cache, store: store}
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP принимает POST с JSON {query, operationName, variables} или GET с теми же
+// параметрами в строке запроса. Ошибки выполнения возвращаются в поле errors со статусом 200.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query, req.OperationName = query.Get("query"), query.Get("operationName")
+		if raw := query.Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				http.Error(w, "Invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	// Загрузчик живет один запрос: заказы, запрошенные разными полями, грузятся одной пачкой
+	ctx := context.WithValue(r.Context(), loaderKey{}, h.newLoader())
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
+}
+
+type loaderKey struct{}
+
+type orderLoader = dataloader.Loader[string, *models.Order]
+
+func ordersLoader(ctx context.Context) *orderLoader {
+	return ctx.Value(loaderKey{}).(*orderLoader)
+}
+
+// newLoader собирает uid, запрошенные за wait, и отдает кэшу сервиса; промахи
+// загружаются из хранилища одним вызовом GetOrders. Отсутствующий заказ - nil без ошибки.
+func (h *Handler) newLoader() *orderLoader {
+	batch := func(ctx context.Context, uids []string) []*dataloader.Result[*models.Order] {
+		results := make([]*dataloader.Result[*models.Order], len(uids))
+		var missing []string
+		for i, uid := range uids {
+			if order, ok := h.cache.Get(uid); ok {
+				results[i] = &dataloader.Result[*models.Order]{Data: order}
+			} else {
+				missing = append(missing, uid)
+			}
+		}
+		if len(missing) == 0 {
+			return results
+		}
+
+		orders, err := h.store.GetOrders(ctx, missing)
+		byUID := make(map[string]*models.Order, len(orders))
+		for _, order := range orders {
+			byUID[order.OrderUID] = order
+		}
+		for i, uid := range uids {
+			if results[i] == nil {
+				results[i] = &dataloader.Result[*models.Order]{Data: byUID[uid], Error: err}
+			}
+		}
+		return results
+	}
+	return dataloader.NewBatchedLoader(batch,
+		dataloader.WithWait[string, *models.Order](2*time.Millisecond),
+		dataloader.WithBatchCapacity[string, *models.Order](maxPageSize),
+	)
+}
+
+// internalError скрывает от клиента подробности ошибки хранилища.
+func internalError(ctx context.Context, err error) error {
+	slog.ErrorContext(ctx, "GraphQL resolver failed", "error", err)
+	return errInternal
+}
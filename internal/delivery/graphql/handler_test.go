@@ -0,0 +1,277 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"order-service/internal/cache"
+	"order-service/internal/models"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	mu     sync.Mutex
+	orders map[string]*models.Order
+	// batches - аргументы каждого вызова GetOrders
+	batches [][]string
+	counts  int
+}
+
+func newFakeStore(n int) *fakeStore {
+	s := &fakeStore{orders: map[string]*models.Order{}}
+	for i := 1; i <= n; i++ {
+		status := models.StatusCreated
+		if i%2 == 0 {
+			status = models.StatusShipped
+		}
+		uid := fmt.Sprintf("order-%02d", i)
+		s.orders[uid] = &models.Order{
+			OrderUID:    uid,
+			TrackNumber: fmt.Sprintf("TRACK-%02d", i),
+			CustomerID:  fmt.Sprintf("customer-%d", i%3),
+			DateCreated: time.Date(2025, 10, i, 0, 0, 0, 0, time.UTC),
+			Status:      status,
+			Version:     1 << 40,
+			Delivery:    models.Delivery{Name: "Test Testov", City: fmt.Sprintf("City %d", i)},
+			Payment:     models.Payment{Amount: 1000 + i, PaymentDt: 1637907727},
+			Items:       []models.Item{{ChrtID: i, Name: "Mascaras", Status: models.ItemPending, Quantity: 1}},
+		}
+	}
+	return s
+}
+
+func (s *fakeStore) GetOrders(ctx context.Context, uids []string) ([]*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]string(nil), uids...))
+	var orders []*models.Order
+	for _, uid := range uids {
+		if order, ok := s.orders[uid]; ok {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (s *fakeStore) FindOrders(ctx context.Context, filter models.OrderFilter, after string, limit int) ([]string, error) {
+	uids := s.match(filter)
+	start := sort.SearchStrings(uids, after+"\x00")
+	if after == "" {
+		start = 0
+	}
+	return uids[start:min(start+limit, len(uids))], nil
+}
+
+func (s *fakeStore) CountOrders(ctx context.Context, filter models.OrderFilter) (int, error) {
+	s.mu.Lock()
+	s.counts++
+	s.mu.Unlock()
+	return len(s.match(filter)), nil
+}
+
+func (s *fakeStore) match(filter models.OrderFilter) []string {
+	var uids []string
+	for uid, order := range s.orders {
+		if filter.Match(order) {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func query(t *testing.T, h http.Handler, q string, variables map[string]any) response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": q, "variables": variables})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp response
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestHandler_Order(t *testing.T) {
+	store := newFakeStore(3)
+	h := NewHandler(cache.New(), store)
+
+	resp := query(t, h, `query($id: ID!) { order(id: $id) { status delivery { city } version payment { paymentDt } } }`,
+		map[string]any{"id": "order-02"})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %v", resp.Errors)
+	}
+	// Возвращаются только запрошенные поля
+	want := `{"status":"SHIPPED","delivery":{"city":"City 2"},"version":1099511627776,"payment":{"paymentDt":1637907727}}`
+	if got := string(resp.Data["order"]); got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+
+	resp = query(t, h, `{ order(id: "missing") { id } byTrack: orderByTrackNumber(trackNumber: "TRACK-03") { id dateCreated } }`, nil)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %v", resp.Errors)
+	}
+	if got := string(resp.Data["order"]); got != "null" {
+		t.Errorf("missing order = %s, want null", got)
+	}
+	if got := string(resp.Data["byTrack"]); got != `{"id":"order-03","dateCreated":"2025-10-03T00:00:00Z"}` {
+		t.Errorf("byTrack = %s", got)
+	}
+}
+
+func TestHandler_Batching(t *testing.T) {
+	store := newFakeStore(30)
+	c := cache.New()
+	c.Set(store.orders["order-01"])
+	h := NewHandler(c, store)
+
+	// Страница из 10 заказов и три заказа по id под алиасами - один вызов GetOrders на
+	// все, чего нет в кэше
+	resp := query(t, h, `{
+		orders(first: 10) { edges { node { id items { name } } } }
+		a: order(id: "order-01") { id }
+		b: order(id: "order-20") { id }
+		c: order(id: "order-21") { id }
+	}`, nil)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %v", resp.Errors)
+	}
+	total := 0
+	for _, batch := range store.batches {
+		total += len(batch)
+	}
+	if len(store.batches) > 2 || total != 11 {
+		t.Errorf("GetOrders calls = %v, want the 11 uncached orders in at most two batches", store.batches)
+	}
+}
+
+func TestHandler_Connection(t *testing.T) {
+	store := newFakeStore(10)
+	h := NewHandler(cache.New(), store)
+
+	q := `query($after: String) {
+		ordersByCustomer(customerId: "customer-1", first: 2, after: $after) {
+			edges { cursor node { id } }
+			pageInfo { hasNextPage endCursor }
+			totalCount
+		}
+	}`
+	var ids []string
+	var after any
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination does not terminate")
+		}
+		resp := query(t, h, q, map[string]any{"after": after})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("errors: %v", resp.Errors)
+		}
+		var conn struct {
+			Edges []struct {
+				Node struct{ ID string }
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   *string
+			}
+			TotalCount int
+		}
+		json.Unmarshal(resp.Data["ordersByCustomer"], &conn)
+		if conn.TotalCount != 4 {
+			t.Errorf("totalCount = %d, want 4", conn.TotalCount)
+		}
+		for _, edge := range conn.Edges {
+			ids = append(ids, edge.Node.ID)
+		}
+		if !conn.PageInfo.HasNextPage {
+			break
+		}
+		after = *conn.PageInfo.EndCursor
+	}
+	if fmt.Sprint(ids) != "[order-01 order-04 order-07 order-10]" {
+		t.Errorf("ids = %v", ids)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Filter", `{ orders(filter: {status: [SHIPPED], customerId: "customer-2"}) { edges { node { id } } } }`,
+			`{"edges":[{"node":{"id":"order-02"}},{"node":{"id":"order-08"}}]}`},
+		// Ни один заказ не в статусе UNKNOWN: фильтр не должен пропасть
+		{"Unknown status", `{ orders(filter: {status: [UNKNOWN]}) { edges { node { id } } } }`,
+			`{"edges":[]}`},
+		{"Empty", `{ orders(filter: {trackNumber: "none"}) { edges { node { id } } pageInfo { hasNextPage endCursor } } }`,
+			`{"edges":[],"pageInfo":{"hasNextPage":false,"endCursor":null}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := query(t, h, tt.query, nil)
+			if len(resp.Errors) > 0 {
+				t.Fatalf("errors: %v", resp.Errors)
+			}
+			if got := string(resp.Data["orders"]); got != tt.want {
+				t.Errorf("orders = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// totalCount не выбран - подсчет не выполняется
+	store.counts = 0
+	query(t, h, `{ orders { edges { cursor } } }`, nil)
+	if store.counts != 0 {
+		t.Errorf("CountOrders called %d times without totalCount", store.counts)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	h := NewHandler(cache.New(), newFakeStore(1))
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"Page too large", `{ orders(first: 1000) { totalCount } }`},
+		{"Bad cursor", `{ orders(after: "!!!") { totalCount } }`},
+		{"Unknown field", `{ order(id: "order-01") { password } }`},
+		{"Unknown status", `{ orders(filter: {status: [LOST]}) { totalCount } }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := query(t, h, tt.query, nil); len(resp.Errors) == 0 {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	// GET с запросом в строке
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`{ order(id: "order-01") { id } }`), nil))
+	if !strings.Contains(rr.Body.String(), `"id":"order-01"`) {
+		t.Errorf("GET response = %s", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader("not json")))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rr.Code)
+	}
+}
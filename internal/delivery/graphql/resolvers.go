@@ -0,0 +1,295 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"order-service/internal/models"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const maxPageSize = 100
+
+var errInternal = errors.New("internal error")
+
+type resolver struct {
+	store OrderStore
+}
+
+func (r *resolver) Order(ctx context.Context, args struct{ ID graphql.ID }) (*orderResolver, error) {
+	return loadOrder(ctx, string(args.ID))
+}
+
+func (r *resolver) OrderByTrackNumber(ctx context.Context, args struct{ TrackNumber string }) (*orderResolver, error) {
+	uids, err := r.store.FindOrders(ctx, models.OrderFilter{TrackNumber: args.TrackNumber}, "", 1)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	return loadOrder(ctx, uids[0])
+}
+
+func (r *resolver) OrdersByCustomer(ctx context.Context, args struct {
+	CustomerID graphql.ID
+	Status     *[]string
+	First      int32
+	After      *string
+}) (*connectionResolver, error) {
+	filter := models.OrderFilter{CustomerID: string(args.CustomerID)}
+	if args.Status != nil {
+		filter.Statuses = parseStatuses(*args.Status)
+	}
+	return r.connection(ctx, filter, args.First, args.After)
+}
+
+type filterInput struct {
+	CustomerID      *graphql.ID
+	TrackNumber     *string
+	DeliveryService *string
+	Status          *[]string
+}
+
+func (r *resolver) Orders(ctx context.Context, args struct {
+	Filter *filterInput
+	First  int32
+	After  *string
+}) (*connectionResolver, error) {
+	var filter models.OrderFilter
+	if f := args.Filter; f != nil {
+		if f.CustomerID != nil {
+			filter.CustomerID = string(*f.CustomerID)
+		}
+		if f.TrackNumber != nil {
+			filter.TrackNumber = *f.TrackNumber
+		}
+		if f.DeliveryService != nil {
+			filter.DeliveryService = *f.DeliveryService
+		}
+		if f.Status != nil {
+			filter.Statuses = parseStatuses(*f.Status)
+		}
+	}
+	return r.connection(ctx, filter, args.First, args.After)
+}
+
+// connection запрашивает на один uid больше страницы, чтобы узнать, есть ли следующая.
+// Сами заказы загружаются через loader одним запросом на страницу.
+func (r *resolver) connection(ctx context.Context, filter models.OrderFilter, first int32, cursor *string) (*connectionResolver, error) {
+	if first < 0 || first > maxPageSize {
+		return nil, fmt.Errorf("first must be between 0 and %d", maxPageSize)
+	}
+	var after string
+	if cursor != nil && *cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(*cursor)
+		if err != nil || len(raw) == 0 {
+			return nil, errors.New("invalid cursor")
+		}
+		after = string(raw)
+	}
+
+	uids, err := r.store.FindOrders(ctx, filter, after, int(first)+1)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+	conn := &connectionResolver{store: r.store, filter: filter}
+	if len(uids) > int(first) {
+		uids, conn.hasNext = uids[:first], true
+	}
+
+	thunks := make([]func() (*models.Order, error), len(uids))
+	for i, uid := range uids {
+		thunks[i] = ordersLoader(ctx).Load(ctx, uid)
+	}
+	for i, uid := range uids {
+		order, err := thunks[i]()
+		if err != nil {
+			return nil, internalError(ctx, err)
+		}
+		// Заказ удален между поиском и загрузкой
+		if order == nil {
+			continue
+		}
+		conn.edges = append(conn.edges, &edgeResolver{cursor: encodeCursor(uid), node: &orderResolver{order}})
+	}
+	return conn, nil
+}
+
+func loadOrder(ctx context.Context, uid string) (*orderResolver, error) {
+	order, err := ordersLoader(ctx).Load(ctx, uid)()
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+	if order == nil {
+		return nil, nil
+	}
+	return &orderResolver{order}, nil
+}
+
+func encodeCursor(uid string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(uid))
+}
+
+// statusByName - коды по значениям enum OrderStatus, включая UNKNOWN, который
+// ParseOrderStatus не принимает.
+var statusByName = func() map[string]models.OrderStatus {
+	names := make(map[string]models.OrderStatus)
+	for status := models.StatusUnknown; status <= models.StatusReturned; status++ {
+		names[strings.ToUpper(status.String())] = status
+	}
+	return names
+}()
+
+// parseStatuses переводит значения enum OrderStatus; схема уже проверила, что они известны.
+func parseStatuses(names []string) []models.OrderStatus {
+	statuses := make([]models.OrderStatus, 0, len(names))
+	for _, name := range names {
+		statuses = append(statuses, statusByName[name])
+	}
+	return statuses
+}
+
+type connectionResolver struct {
+	store   OrderStore
+	filter  models.OrderFilter
+	edges   []*edgeResolver
+	hasNext bool
+}
+
+func (c *connectionResolver) Edges() []*edgeResolver { return c.edges }
+
+func (c *connectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNext: c.hasNext}
+	if len(c.edges) > 0 {
+		info.endCursor = &c.edges[len(c.edges)-1].cursor
+	}
+	return info
+}
+
+// TotalCount считается отдельным запросом, только если поле выбрано.
+func (c *connectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := c.store.CountOrders(ctx, c.filter)
+	if err != nil {
+		return 0, internalError(ctx, err)
+	}
+	return int32(count), nil
+}
+
+type edgeResolver struct {
+	cursor string
+	node   *orderResolver
+}
+
+func (e *edgeResolver) Cursor() string       { return e.cursor }
+func (e *edgeResolver) Node() *orderResolver { return e.node }
+
+type pageInfoResolver struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (p *pageInfoResolver) HasNextPage() bool  { return p.hasNext }
+func (p *pageInfoResolver) EndCursor() *string { return p.endCursor }
+
+// Int64 - скаляр для version и payment_dt, которые не помещаются в Int GraphQL.
+type Int64 int64
+
+func (Int64) ImplementsGraphQLType(name string) bool { return name == "Int64" }
+
+func (n *Int64) UnmarshalGraphQL(input any) error {
+	switch v := input.(type) {
+	case int32:
+		*n = Int64(v)
+	case int64:
+		*n = Int64(v)
+	case float64:
+		*n = Int64(v)
+	default:
+		return fmt.Errorf("wrong type for Int64: %T", input)
+	}
+	return nil
+}
+
+type orderResolver struct {
+	o *models.Order
+}
+
+func (r *orderResolver) ID() graphql.ID              { return graphql.ID(r.o.OrderUID) }
+func (r *orderResolver) TrackNumber() string         { return r.o.TrackNumber }
+func (r *orderResolver) Entry() string               { return r.o.Entry }
+func (r *orderResolver) Delivery() *deliveryResolver { return &deliveryResolver{r.o.Delivery} }
+func (r *orderResolver) Payment() *paymentResolver   { return &paymentResolver{r.o.Payment} }
+func (r *orderResolver) Locale() string              { return r.o.Locale }
+func (r *orderResolver) InternalSignature() string   { return r.o.InternalSignature }
+func (r *orderResolver) CustomerID() graphql.ID      { return graphql.ID(r.o.CustomerID) }
+func (r *orderResolver) DeliveryService() string     { return r.o.DeliveryService }
+func (r *orderResolver) Shardkey() string            { return r.o.Shardkey }
+func (r *orderResolver) SmID() int32                 { return int32(r.o.SmID) }
+func (r *orderResolver) DateCreated() graphql.Time   { return graphql.Time{Time: r.o.DateCreated} }
+func (r *orderResolver) OofShard() string            { return r.o.OofShard }
+func (r *orderResolver) Status() string              { return strings.ToUpper(r.o.Status.String()) }
+func (r *orderResolver) Version() Int64              { return Int64(r.o.Version) }
+func (r *orderResolver) FulfillmentStatus() string   { return string(r.o.Fulfillment) }
+
+func (r *orderResolver) Items() []*itemResolver {
+	items := make([]*itemResolver, len(r.o.Items))
+	for i := range r.o.Items {
+		items[i] = &itemResolver{r.o.Items[i]}
+	}
+	return items
+}
+
+func (r *orderResolver) Warnings() []*warningResolver {
+	warnings := make([]*warningResolver, len(r.o.Warnings))
+	for i := range r.o.Warnings {
+		warnings[i] = &warningResolver{r.o.Warnings[i]}
+	}
+	return warnings
+}
+
+type deliveryResolver struct{ d models.Delivery }
+
+func (r *deliveryResolver) Name() string    { return r.d.Name }
+func (r *deliveryResolver) Phone() string   { return r.d.Phone }
+func (r *deliveryResolver) Zip() string     { return r.d.Zip }
+func (r *deliveryResolver) City() string    { return r.d.City }
+func (r *deliveryResolver) Address() string { return r.d.Address }
+func (r *deliveryResolver) Region() string  { return r.d.Region }
+func (r *deliveryResolver) Email() string   { return r.d.Email }
+
+type paymentResolver struct{ p models.Payment }
+
+func (r *paymentResolver) Transaction() string { return r.p.Transaction }
+func (r *paymentResolver) RequestID() string   { return r.p.RequestID }
+func (r *paymentResolver) Currency() string    { return r.p.Currency }
+func (r *paymentResolver) Provider() string    { return r.p.Provider }
+func (r *paymentResolver) Amount() int32       { return int32(r.p.Amount) }
+func (r *paymentResolver) PaymentDt() Int64    { return Int64(r.p.PaymentDt) }
+func (r *paymentResolver) Bank() string        { return r.p.Bank }
+func (r *paymentResolver) DeliveryCost() int32 { return int32(r.p.DeliveryCost) }
+func (r *paymentResolver) GoodsTotal() int32   { return int32(r.p.GoodsTotal) }
+func (r *paymentResolver) CustomFee() int32    { return int32(r.p.CustomFee) }
+
+type itemResolver struct{ i models.Item }
+
+func (r *itemResolver) ChrtID() int32       { return int32(r.i.ChrtID) }
+func (r *itemResolver) TrackNumber() string { return r.i.TrackNumber }
+func (r *itemResolver) Price() int32        { return int32(r.i.Price) }
+func (r *itemResolver) Rid() string         { return r.i.Rid }
+func (r *itemResolver) Name() string        { return r.i.Name }
+func (r *itemResolver) Sale() int32         { return int32(r.i.Sale) }
+func (r *itemResolver) Size() string        { return r.i.Size }
+func (r *itemResolver) TotalPrice() int32   { return int32(r.i.TotalPrice) }
+func (r *itemResolver) NmID() int32         { return int32(r.i.NmID) }
+func (r *itemResolver) Brand() string       { return r.i.Brand }
+func (r *itemResolver) Status() string      { return string(r.i.Status) }
+func (r *itemResolver) Quantity() int32     { return int32(r.i.Quantity) }
+
+type warningResolver struct{ w models.Warning }
+
+func (r *warningResolver) Field() string   { return r.w.Field }
+func (r *warningResolver) Message() string { return r.w.Message }
@@ -0,0 +1,299 @@
+// Package grpc - gRPC API чтения заказов (order.v1.OrderService) поверх того же
+// кэша, репозитория и потока изменений, что и HTTP API.
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"order-service/internal/cache"
+	"order-service/internal/logging"
+	"order-service/internal/models"
+	"order-service/internal/orderpb"
+	"order-service/internal/stream"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// OrderReader - хранилище заказов: промахи кэша и постраничный поиск.
+type OrderReader interface {
+	GetOrder(uid string) (*models.Order, error)
+	GetOrders(ctx context.Context, uids []string) ([]*models.Order, error)
+	FindOrders(ctx context.Context, filter models.OrderFilter, after string, limit int) ([]string, error)
+	CountOrders(ctx context.Context, filter models.OrderFilter) (int, error)
+}
+
+type Server struct {
+	orderpb.UnimplementedOrderServiceServer
+
+	cache *cache.Cache
+	repo  OrderReader
+	hub   *stream.Hub
+}
+
+func NewServer(cache *cache.Cache, repo OrderReader, hub *stream.Hub) *Server {
+	return &Server{cache: cache, repo: repo, hub: hub}
+}
+
+// NewGRPCServer собирает grpc.Server с OrderService, health и reflection.
+func NewGRPCServer(srv *Server, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(unaryLogging), grpc.ChainStreamInterceptor(streamLogging))
+	s := grpc.NewServer(opts...)
+	orderpb.RegisterOrderServiceServer(s, srv)
+
+	hs := health.NewServer()
+	hs.SetServingStatus(orderpb.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, hs)
+	reflection.Register(s)
+	return s
+}
+
+func (s *Server) GetOrder(ctx context.Context, req *orderpb.GetOrderRequest) (*orderpb.Order, error) {
+	if req.GetOrderUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+	}
+
+	order, err := s.lookup(req.GetOrderUid())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error loading order", "order_uid", req.GetOrderUid(), "error", err)
+		return nil, status.Error(codes.Internal, "failed to load order")
+	}
+	if order == nil {
+		return nil, status.Errorf(codes.NotFound, "order %s not found", req.GetOrderUid())
+	}
+	return orderpb.FromModel(order), nil
+}
+
+// lookup ищет заказ в кэше, а при промахе - в репозитории. Возвращает nil, nil, если заказа нет.
+func (s *Server) lookup(uid string) (*models.Order, error) {
+	if order, exists := s.cache.Get(uid); exists {
+		return order, nil
+	}
+	if s.repo == nil {
+		return nil, nil
+	}
+
+	order, err := s.repo.GetOrder(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(order)
+	return order, nil
+}
+
+func (s *Server) ListOrders(ctx context.Context, req *orderpb.ListOrdersRequest) (*orderpb.ListOrdersResponse, error) {
+	page, next, total, err := s.page(ctx, models.OrderFilter{}, req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.ListOrdersResponse{Orders: page, NextPageToken: next, TotalSize: total}, nil
+}
+
+func (s *Server) SearchOrders(ctx context.Context, req *orderpb.SearchOrdersRequest) (*orderpb.SearchOrdersResponse, error) {
+	filter := models.OrderFilter{
+		CustomerID:      req.GetCustomerId(),
+		TrackNumber:     req.GetTrackNumber(),
+		DeliveryService: req.GetDeliveryService(),
+	}
+	for _, st := range req.GetStatuses() {
+		filter.Statuses = append(filter.Statuses, models.OrderStatus(st))
+	}
+	if req.GetCreatedFrom() != nil {
+		filter.CreatedFrom = req.GetCreatedFrom().AsTime()
+	}
+	if req.GetCreatedTo() != nil {
+		filter.CreatedTo = req.GetCreatedTo().AsTime()
+	}
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return nil, status.Error(codes.InvalidArgument, "created_from must be before created_to")
+	}
+
+	page, next, total, err := s.page(ctx, filter, req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &orderpb.SearchOrdersResponse{Orders: page, NextPageToken: next, TotalSize: total}, nil
+}
+
+// page ищет заказы по filter в репозитории в порядке order_uid. Токен страницы - order_uid
+// последнего отданного заказа, поэтому вставки между запросами не сдвигают страницы.
+// Сами заказы берутся из кэша, промахи дочитываются одним запросом.
+func (s *Server) page(ctx context.Context, filter models.OrderFilter, size int32, token string) ([]*orderpb.Order, string, int32, error) {
+	switch {
+	case size < 0:
+		return nil, "", 0, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case size == 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+	var after string
+	if token != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(raw) == 0 {
+			return nil, "", 0, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		after = string(raw)
+	}
+	if s.repo == nil {
+		return nil, "", 0, status.Error(codes.Unavailable, "order repository is not configured")
+	}
+
+	// Лишний uid показывает, есть ли следующая страница
+	uids, err := s.repo.FindOrders(ctx, filter, after, int(size)+1)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error searching orders", "error", err)
+		return nil, "", 0, status.Error(codes.Internal, "failed to search orders")
+	}
+	var next string
+	if len(uids) > int(size) {
+		uids = uids[:size]
+		next = base64.RawURLEncoding.EncodeToString([]byte(uids[len(uids)-1]))
+	}
+	total, err := s.repo.CountOrders(ctx, filter)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error counting orders", "error", err)
+		return nil, "", 0, status.Error(codes.Internal, "failed to count orders")
+	}
+
+	found := make(map[string]*models.Order, len(uids))
+	var missing []string
+	for _, uid := range uids {
+		if order, ok := s.cache.Get(uid); ok {
+			found[uid] = order
+		} else {
+			missing = append(missing, uid)
+		}
+	}
+	if len(missing) > 0 {
+		orders, err := s.repo.GetOrders(ctx, missing)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error loading orders", "error", err)
+			return nil, "", 0, status.Error(codes.Internal, "failed to load orders")
+		}
+		for _, order := range orders {
+			s.cache.Set(order)
+			found[order.OrderUID] = order
+		}
+	}
+
+	page := make([]*orderpb.Order, 0, len(uids))
+	for _, uid := range uids {
+		// Заказ удален между поиском и загрузкой
+		if order, ok := found[uid]; ok {
+			page = append(page, orderpb.FromModel(order))
+		}
+	}
+	return page, next, int32(total), nil
+}
+
+// WatchOrders передает изменения заказов до отмены вызова. Клиент, который не успевает
+// читать, отключается с UNAVAILABLE и может переподключиться с last_event_id.
+func (s *Server) WatchOrders(req *orderpb.WatchOrdersRequest, srv orderpb.OrderService_WatchOrdersServer) error {
+	filter := stream.Filter{CustomerID: req.GetCustomerId(), OrderUID: req.GetOrderUid()}
+	for _, st := range req.GetStatuses() {
+		filter.Statuses = append(filter.Statuses, models.OrderStatus(st))
+	}
+
+	sub, backlog, complete := s.hub.Subscribe(filter, req.GetLastEventId(), req.GetLastEventId() != 0)
+	defer sub.Close()
+	// Заголовки сразу после подписки: получив их, клиент знает, что дальнейшие изменения не пропустит
+	if err := srv.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	if !complete {
+		if err := srv.Send(&orderpb.WatchOrdersResponse{Resync: true}); err != nil {
+			return err
+		}
+	}
+	for _, update := range backlog {
+		if err := send(srv, update); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case update, ok := <-sub.Updates():
+			if !ok {
+				slog.WarnContext(srv.Context(), "Order watch client is too slow, disconnecting")
+				return status.Error(codes.Unavailable, "client is too slow, resubscribe with last_event_id")
+			}
+			if err := send(srv, update); err != nil {
+				return err
+			}
+		case <-srv.Context().Done():
+			return nil
+		}
+	}
+}
+
+func send(srv orderpb.OrderService_WatchOrdersServer, update stream.Update) error {
+	return srv.Send(&orderpb.WatchOrdersResponse{EventId: update.ID, Order: orderpb.FromModel(update.Order)})
+}
+
+// Как и HTTP Logging: correlation id из метаданных x-request-id или новый.
+func unaryLogging(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx = withRequestID(ctx)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func streamLogging(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := withRequestID(ss.Context())
+	start := time.Now()
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if values := metadata.ValueFromIncomingContext(ctx, "x-request-id"); len(values) > 0 {
+		id = values[0]
+	}
+	if id == "" {
+		b := make([]byte, 8)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	return logging.WithCorrelationID(ctx, id)
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	slog.InfoContext(ctx, "gRPC request",
+		"method", method,
+		"code", status.Code(err).String(),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
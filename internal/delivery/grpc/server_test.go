@@ -0,0 +1,325 @@
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"order-service/internal/cache"
+	"order-service/internal/models"
+	"order-service/internal/orderpb"
+	"order-service/internal/stream"
+	"sort"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakeRepo struct {
+	orders map[string]*models.Order
+	loaded []string
+}
+
+func (r *fakeRepo) GetOrder(uid string) (*models.Order, error) {
+	if order, ok := r.orders[uid]; ok {
+		return order, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeRepo) GetOrders(ctx context.Context, uids []string) ([]*models.Order, error) {
+	r.loaded = append(r.loaded, uids...)
+	var orders []*models.Order
+	for _, uid := range uids {
+		if order, ok := r.orders[uid]; ok {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *fakeRepo) FindOrders(ctx context.Context, filter models.OrderFilter, after string, limit int) ([]string, error) {
+	var uids []string
+	for _, uid := range r.match(filter) {
+		if uid > after && len(uids) < limit {
+			uids = append(uids, uid)
+		}
+	}
+	return uids, nil
+}
+
+func (r *fakeRepo) CountOrders(ctx context.Context, filter models.OrderFilter) (int, error) {
+	return len(r.match(filter)), nil
+}
+
+func (r *fakeRepo) match(filter models.OrderFilter) []string {
+	var uids []string
+	for uid, order := range r.orders {
+		if filter.Match(order) {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+// startServer запускает сервер на bufconn и возвращает подключенный клиент.
+func startServer(t *testing.T, c *cache.Cache, repo OrderReader, hub *stream.Hub) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := NewGRPCServer(NewServer(c, repo, hub))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// testOrders кладет пять заказов в репозиторий, а в кэш - только первые три,
+// как после вытеснения по cache.max_orders.
+func testOrders(c *cache.Cache) *fakeRepo {
+	repo := &fakeRepo{orders: map[string]*models.Order{}}
+	created := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		status := models.StatusCreated
+		if i%2 == 0 {
+			status = models.StatusShipped
+		}
+		order := &models.Order{
+			OrderUID:        fmt.Sprintf("order-%d", i),
+			TrackNumber:     fmt.Sprintf("TRACK-%d", i),
+			CustomerID:      fmt.Sprintf("customer-%d", i%2),
+			DeliveryService: "meest",
+			DateCreated:     created.AddDate(0, 0, i),
+			Status:          status,
+			Items:           []models.Item{{ChrtID: i, Name: "Mascaras", Status: models.ItemPending}},
+		}
+		repo.orders[order.OrderUID] = order
+		if i <= 3 {
+			c.Set(order)
+		}
+	}
+	return repo
+}
+
+func TestServer_GetOrder(t *testing.T) {
+	c := cache.New()
+	repo := testOrders(c)
+	repo.orders["evicted"] = &models.Order{OrderUID: "evicted", TrackNumber: "TRACK-DB"}
+	client := orderpb.NewOrderServiceClient(startServer(t, c, repo, stream.NewHub(10, 10)))
+	ctx := context.Background()
+
+	order, err := client.GetOrder(ctx, &orderpb.GetOrderRequest{OrderUid: "order-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetTrackNumber() != "TRACK-2" || order.GetStatus() != orderpb.OrderStatus_ORDER_STATUS_SHIPPED || len(order.GetItems()) != 1 {
+		t.Errorf("unexpected order %v", order)
+	}
+
+	// Промах кэша дочитывается из репозитория
+	order, err = client.GetOrder(ctx, &orderpb.GetOrderRequest{OrderUid: "evicted"})
+	if err != nil || order.GetTrackNumber() != "TRACK-DB" {
+		t.Errorf("evicted order: %v, %v", order, err)
+	}
+
+	tests := []struct {
+		uid  string
+		code codes.Code
+	}{
+		{"missing", codes.NotFound},
+		{"", codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		_, err := client.GetOrder(ctx, &orderpb.GetOrderRequest{OrderUid: tt.uid})
+		if status.Code(err) != tt.code {
+			t.Errorf("GetOrder(%q): code %v, want %v", tt.uid, status.Code(err), tt.code)
+		}
+	}
+}
+
+func TestServer_ListOrders(t *testing.T) {
+	c := cache.New()
+	repo := testOrders(c)
+	client := orderpb.NewOrderServiceClient(startServer(t, c, repo, stream.NewHub(10, 10)))
+	ctx := context.Background()
+
+	var uids []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination does not terminate")
+		}
+		resp, err := client.ListOrders(ctx, &orderpb.ListOrdersRequest{PageSize: 2, PageToken: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetTotalSize() != 5 {
+			t.Errorf("total_size = %d, want 5", resp.GetTotalSize())
+		}
+		for _, order := range resp.GetOrders() {
+			uids = append(uids, order.GetOrderUid())
+		}
+		if token = resp.GetNextPageToken(); token == "" {
+			break
+		}
+	}
+	if fmt.Sprint(uids) != "[order-1 order-2 order-3 order-4 order-5]" {
+		t.Errorf("orders = %v", uids)
+	}
+	// Из репозитория дочитываются только вытесненные из кэша
+	if fmt.Sprint(repo.loaded) != "[order-4 order-5]" {
+		t.Errorf("loaded from repository: %v", repo.loaded)
+	}
+
+	_, err := client.ListOrders(ctx, &orderpb.ListOrdersRequest{PageToken: "!!!"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("invalid token: code %v", status.Code(err))
+	}
+
+	noRepo := orderpb.NewOrderServiceClient(startServer(t, c, nil, stream.NewHub(10, 10)))
+	if _, err := noRepo.ListOrders(ctx, &orderpb.ListOrdersRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("without repository: code %v", status.Code(err))
+	}
+}
+
+func TestServer_SearchOrders(t *testing.T) {
+	c := cache.New()
+	client := orderpb.NewOrderServiceClient(startServer(t, c, testOrders(c), stream.NewHub(10, 10)))
+
+	tests := []struct {
+		name string
+		req  *orderpb.SearchOrdersRequest
+		want string
+		code codes.Code
+	}{
+		{"By customer", &orderpb.SearchOrdersRequest{CustomerId: "customer-1"}, "[order-1 order-3 order-5]", codes.OK},
+		{"By status", &orderpb.SearchOrdersRequest{Statuses: []orderpb.OrderStatus{orderpb.OrderStatus_ORDER_STATUS_SHIPPED}}, "[order-2 order-4]", codes.OK},
+		{"By track number", &orderpb.SearchOrdersRequest{TrackNumber: "TRACK-3"}, "[order-3]", codes.OK},
+		{"By date range", &orderpb.SearchOrdersRequest{
+			CreatedFrom: timestamppb.New(time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)),
+			CreatedTo:   timestamppb.New(time.Date(2025, 10, 5, 0, 0, 0, 0, time.UTC)),
+		}, "[order-2 order-3]", codes.OK},
+		{"Combined", &orderpb.SearchOrdersRequest{CustomerId: "customer-1", DeliveryService: "meest", Statuses: []orderpb.OrderStatus{orderpb.OrderStatus_ORDER_STATUS_CREATED}, PageSize: 2}, "[order-1 order-3]", codes.OK},
+		{"No match", &orderpb.SearchOrdersRequest{CustomerId: "nobody"}, "[]", codes.OK},
+		{"Inverted range", &orderpb.SearchOrdersRequest{
+			CreatedFrom: timestamppb.New(time.Date(2025, 10, 5, 0, 0, 0, 0, time.UTC)),
+			CreatedTo:   timestamppb.New(time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)),
+		}, "", codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.SearchOrders(context.Background(), tt.req)
+			if status.Code(err) != tt.code {
+				t.Fatalf("code %v, want %v: %v", status.Code(err), tt.code, err)
+			}
+			if err != nil {
+				return
+			}
+			uids := []string{}
+			for _, order := range resp.GetOrders() {
+				uids = append(uids, order.GetOrderUid())
+			}
+			if fmt.Sprint(uids) != tt.want {
+				t.Errorf("orders = %v, want %s", uids, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_WatchOrders(t *testing.T) {
+	c := cache.New()
+	hub := stream.NewHub(10, 10)
+	c.OnSet(hub.Publish)
+	client := orderpb.NewOrderServiceClient(startServer(t, c, nil, hub))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c.Set(&models.Order{OrderUID: "order-1", CustomerID: "customer-1", Status: models.StatusCreated})
+
+	watch, err := client.WatchOrders(ctx, &orderpb.WatchOrdersRequest{CustomerId: "customer-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Заголовки приходят после регистрации подписки
+	if _, err := watch.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Set(&models.Order{OrderUID: "order-2", CustomerID: "customer-2", Status: models.StatusCreated})
+	c.Set(&models.Order{OrderUID: "order-1", CustomerID: "customer-1", Status: models.StatusPaid})
+
+	first, err := watch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.GetOrder().GetOrderUid() != "order-1" || first.GetOrder().GetStatus() != orderpb.OrderStatus_ORDER_STATUS_PAID {
+		t.Fatalf("unexpected update %v", first)
+	}
+
+	// Возобновление: пропущенное досылается из буфера
+	c.Set(&models.Order{OrderUID: "order-1", CustomerID: "customer-1", Status: models.StatusAssembling})
+	resumed, err := client.WatchOrders(ctx, &orderpb.WatchOrdersRequest{CustomerId: "customer-1", LastEventId: first.GetEventId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := resumed.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.GetResync() || next.GetEventId() <= first.GetEventId() || next.GetOrder().GetStatus() != orderpb.OrderStatus_ORDER_STATUS_ASSEMBLING {
+		t.Errorf("unexpected resumed update %v", next)
+	}
+
+	// Неизвестный event id - клиенту нужно перечитать состояние
+	stale, err := client.WatchOrders(ctx, &orderpb.WatchOrdersRequest{LastEventId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := stale.Recv(); err != nil || !msg.GetResync() {
+		t.Errorf("expected resync, got %v, %v", msg, err)
+	}
+}
+
+func TestServer_HealthAndReflection(t *testing.T) {
+	conn := startServer(t, cache.New(), nil, stream.NewHub(10, 10))
+	ctx := context.Background()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: orderpb.OrderService_ServiceDesc.ServiceName})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health: %v, %v", resp, err)
+	}
+
+	info, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}})
+	reply, err := info.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, svc := range reply.GetListServicesResponse().GetService() {
+		found = found || svc.GetName() == orderpb.OrderService_ServiceDesc.ServiceName
+	}
+	if !found {
+		t.Errorf("reflection does not list %s", orderpb.OrderService_ServiceDesc.ServiceName)
+	}
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"order-service/internal/config"
+	"time"
+)
+
+type AdminHandler struct {
+	config *config.Store
+}
+
+func NewAdminHandler(config *config.Store) *AdminHandler {
+	return &AdminHandler{config: config}
+}
+
+// Config показывает активную версию конфигурации (секреты замаскированы).
+func (h *AdminHandler) Config(w http.ResponseWriter, r *http.Request) {
+	snapshot := h.config.Current()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"version":   snapshot.Version,
+		"loaded_at": snapshot.LoadedAt.Format(time.RFC3339),
+		"path":      snapshot.Config.Path(),
+		"config":    snapshot.Config.Masked(),
+	})
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// TokenAuth пропускает запросы с заголовком Authorization: Bearer <token>.
+// Токен можно менять на лету; пока он пуст, защищенные маршруты отключены.
+type TokenAuth struct {
+	token atomic.Value
+}
+
+func NewTokenAuth() *TokenAuth {
+	a := &TokenAuth{}
+	a.token.Store("")
+	return a
+}
+
+func (a *TokenAuth) SetToken(token string) {
+	a.token.Store(token)
+}
+
+func (a *TokenAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := a.token.Load().(string)
+		if token == "" {
+			http.Error(w, "Admin API is disabled: admin.token is not set", http.StatusForbidden)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
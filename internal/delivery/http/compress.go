@@ -0,0 +1,253 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Ответы меньше этого размера не сжимаются: выигрыш меньше накладных расходов.
+const compressMinSize = 1024
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type zstdEncoder struct{ *zstd.Encoder }
+
+func (z zstdEncoder) Reset(w io.Writer) { z.Encoder.Reset(w) }
+
+// Кодировки в порядке предпочтения сервера при равном q.
+var codecs = []struct {
+	name string
+	pool *sync.Pool
+}{
+	{"zstd", &sync.Pool{New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return zstdEncoder{enc}
+	}}},
+	{"br", &sync.Pool{New: func() any { return brotli.NewWriterLevel(nil, 4) }}},
+	{"gzip", &sync.Pool{New: func() any { gz, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression); return gz }}},
+}
+
+// negotiateEncoding выбирает кодировку по Accept-Encoding с учетом q-значений (q=0 - запрет).
+// Пустая строка - без сжатия.
+func negotiateEncoding(header string) (string, *sync.Pool) {
+	prefs := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "x-gzip" {
+			name = "gzip"
+		}
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		prefs[name] = q
+	}
+
+	best, bestQ := -1, 0.0
+	for i, codec := range codecs {
+		q, ok := prefs[codec.name]
+		if !ok {
+			q, ok = prefs["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	if best < 0 {
+		return "", nil
+	}
+	return codecs[best].name, codecs[best].pool
+}
+
+// Compress сжимает ответы gzip, brotli или zstd по Accept-Encoding. Сжимаются только
+// текстовые типы от compressMinSize байт; ответы с Content-Encoding, частичные (Range)
+// и потоки событий отдаются как есть. Строгий ETag сжатого ответа получает суффикс
+// кодировки, чтобы разные байты не делили один ETag; в If-None-Match суффикс снимается.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding, pool := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, pool: pool, head: r.Method == http.MethodHead}
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			// Снимаем только суффикс выбранной кодировки: ETag другой кодировки не совпадет
+			stripped := strings.ReplaceAll(inm, "-"+encoding+`"`, `"`)
+			cw.suffixed = stripped != inm
+			r.Header.Set("If-None-Match", stripped)
+		}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter копит начало ответа, пока не станет ясно, стоит ли сжимать.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	pool     *sync.Pool
+	head     bool
+	// Клиент прислал ETag сжатого представления: 304 возвращает его же
+	suffixed bool
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	cw.status = status
+	// Ответы без тела
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified || cw.head {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= compressMinSize {
+		if err := cw.flushBuffer(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide отправляет заголовки: со сжатием, если compress и ответ подходит.
+func (cw *compressWriter) decide(compress bool) {
+	cw.decided = true
+	h := cw.Header()
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if compress && cw.status == http.StatusOK && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		cw.suffixETag()
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		cw.enc = cw.pool.Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	} else if cw.status == http.StatusNotModified && cw.suffixed {
+		cw.suffixETag()
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// suffixETag помечает строгий ETag кодировкой ответа.
+func (cw *compressWriter) suffixETag() {
+	h := cw.Header()
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+cw.encoding+`"`)
+	}
+}
+
+func (cw *compressWriter) flushBuffer(compress bool) error {
+	if !cw.decided {
+		cw.decide(compress)
+	}
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// Flush отправляет накопленное. Если до первого Flush набралось меньше
+// compressMinSize, ответ идет без сжатия.
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	cw.flushBuffer(len(cw.buf) >= compressMinSize)
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Close() error {
+	if cw.status == 0 && len(cw.buf) == 0 {
+		// Хендлер ничего не написал - net/http сам ответит 200 без тела
+		return nil
+	}
+	err := cw.flushBuffer(len(cw.buf) >= compressMinSize)
+	if cw.enc != nil {
+		if cerr := cw.enc.Close(); err == nil {
+			err = cerr
+		}
+		cw.pool.Put(cw.enc)
+		cw.enc = nil
+	}
+	return err
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"x-gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"gzip, br, zstd", "zstd"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"gzip;q=0", ""},
+		{"*", "zstd"},
+		{"*;q=0.1, zstd;q=0, br;q=0", "gzip"},
+		{"GZIP ; q=1.0", "gzip"},
+		{"gzip;q=abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got, _ := negotiateEncoding(tt.header); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	case "br":
+		r = brotli.NewReader(body)
+	case "zstd":
+		zr, err := zstd.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		r = body
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decode %s: %v", encoding, err)
+	}
+	return string(data)
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"items":"` + strings.Repeat("a", 4*compressMinSize) + `"}`
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			serveBody(w, r, "application/json", []byte(large), time.Time{})
+		case "/small":
+			serveBody(w, r, "application/json", []byte(`{"id":"1"}`), time.Time{})
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(strings.Repeat("x", 4*compressMinSize)))
+		case "/error":
+			http.Error(w, strings.Repeat("not found ", compressMinSize), http.StatusNotFound)
+		}
+	}))
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		encoding string
+		status   int
+	}{
+		{"gzip", "/large", "gzip", "gzip", http.StatusOK},
+		{"brotli", "/large", "gzip, br", "br", http.StatusOK},
+		{"zstd", "/large", "gzip, br, zstd", "zstd", http.StatusOK},
+		{"Refused encoding", "/large", "gzip;q=0", "", http.StatusOK},
+		{"Small body", "/small", "gzip", "", http.StatusOK},
+		{"Image", "/image", "gzip", "", http.StatusOK},
+		{"Error response", "/error", "gzip", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", tt.accept)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.status)
+			}
+			if got := rr.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if got := rr.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			if tt.encoding != "" {
+				if rr.Header().Get("Content-Length") != "" {
+					t.Error("Content-Length must be removed from a compressed response")
+				}
+				if got := decode(t, tt.encoding, rr.Body); got != large {
+					t.Errorf("decoded body does not match the original (%d bytes)", len(got))
+				}
+			}
+		})
+	}
+}
+
+func TestCompress_ConditionalGet(t *testing.T) {
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveBody(w, r, "application/json", []byte(strings.Repeat("a", 4*compressMinSize)), time.Time{})
+	}))
+
+	get := func(accept, inm string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		if inm != "" {
+			req.Header.Set("If-None-Match", inm)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	plain := get("", "")
+	gz := get("gzip", "")
+	if !strings.HasSuffix(gz.Header().Get("ETag"), `-gzip"`) {
+		t.Fatalf("ETag = %s, want a -gzip suffix", gz.Header().Get("ETag"))
+	}
+	if plain.Header().Get("ETag") == gz.Header().Get("ETag") {
+		t.Fatal("compressed and plain representations share an ETag")
+	}
+
+	// Повторный запрос с ETag сжатого представления
+	rr := get("gzip", gz.Header().Get("ETag"))
+	if rr.Code != http.StatusNotModified {
+		t.Fatalf("status = %d, want 304", rr.Code)
+	}
+	if rr.Header().Get("ETag") != gz.Header().Get("ETag") || rr.Body.Len() != 0 {
+		t.Errorf("304 response: ETag %s, body %d bytes", rr.Header().Get("ETag"), rr.Body.Len())
+	}
+
+	// Клиент сменил кодировку - старый ETag не подходит
+	if rr := get("br", gz.Header().Get("ETag")); rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 for another encoding", rr.Code)
+	}
+}
+
+// Несжатый ответ отдает исходный ETag: иначе 304 и повторная сверка расходятся
+// с представлением, которое получил клиент.
+func TestCompress_ETagOnlyWhenEncoded(t *testing.T) {
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			serveBody(w, r, "application/json", []byte(`{"id":"1"}`), time.Time{})
+		case "/image":
+			serveBody(w, r, "image/png", []byte(strings.Repeat("x", 4*compressMinSize)), time.Time{})
+		}
+	}))
+	get := func(path, inm string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if inm != "" {
+			req.Header.Set("If-None-Match", inm)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, path := range []string{"/small", "/image"} {
+		t.Run(path, func(t *testing.T) {
+			first := get(path, "")
+			etag := first.Header().Get("ETag")
+			if first.Header().Get("Content-Encoding") != "" {
+				t.Fatalf("Content-Encoding = %q, want none", first.Header().Get("Content-Encoding"))
+			}
+			if etag == "" || strings.HasSuffix(etag, `-gzip"`) {
+				t.Fatalf("ETag = %s, want no encoding suffix", etag)
+			}
+			rr := get(path, etag)
+			if rr.Code != http.StatusNotModified || rr.Header().Get("ETag") != etag {
+				t.Errorf("status = %d, ETag = %s; want 304 with %s", rr.Code, rr.Header().Get("ETag"), etag)
+			}
+		})
+	}
+}
+
+func TestCompress_Range(t *testing.T) {
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "orders.json", time.Time{}, strings.NewReader(strings.Repeat("a", 4*compressMinSize)))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders.json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-9")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusPartialContent || rr.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("status = %d, Content-Encoding = %q; want 206 without encoding", rr.Code, rr.Header().Get("Content-Encoding"))
+	}
+	if rr.Body.String() != "aaaaaaaaaa" {
+		t.Errorf("body = %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,23 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// serveBody отдает body с валидаторами: строгим ETag от содержимого ответа и Last-Modified
+// от modified (нулевое время - без Last-Modified). If-None-Match и If-Modified-Since
+// обрабатывает http.ServeContent и отвечает 304 без тела. Сжатие - в Compress.
+func serveBody(w http.ResponseWriter, r *http.Request, contentType string, body []byte, modified time.Time) {
+	sum := sha256.Sum256(body)
+	etag := hex.EncodeToString(sum[:16])
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+etag+`"`)
+	// Кэш может хранить ответ, но обязан перепроверять его по ETag
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/swaggest/swgui/v5emb"
+)
+
+type DocsHandler struct {
+	spec []byte
+	ui   http.Handler
+}
+
+// NewDocsHandler принимает OpenAPI-спецификацию в JSON; Swagger UI читает ее с /openapi.json.
+func NewDocsHandler(spec []byte) *DocsHandler {
+	return &DocsHandler{spec: spec, ui: v5emb.New("Order Service API", "/openapi.json", "/docs/")}
+}
+
+// Spec - GET /openapi.json
+func (h *DocsHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	serveBody(w, r, "application/json; charset=utf-8", h.spec, time.Time{})
+}
+
+// UI - GET /docs/: Swagger UI, встроенный в бинарник.
+func (h *DocsHandler) UI(w http.ResponseWriter, r *http.Request) {
+	h.ui.ServeHTTP(w, r)
+}
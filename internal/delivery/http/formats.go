@@ -0,0 +1,234 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"order-service/internal/models"
+	"order-service/internal/orderpb"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// format - представление заказов, которое клиент выбирает заголовком Accept.
+// Список заказов в JSON и MessagePack - объект по order_uid, как и раньше;
+// в остальных форматах - последовательность, упорядоченная по order_uid.
+type format struct {
+	contentType string
+	// mediaTypes - типы из Accept; первый - основной
+	mediaTypes []string
+	order      func(*models.Order) ([]byte, error)
+	orders     func(map[string]*models.Order) ([]byte, error)
+}
+
+// formats в порядке предпочтения сервера: при равном q и при Accept: */* - JSON.
+var formats = []format{
+	{
+		contentType: "application/json; charset=utf-8",
+		mediaTypes:  []string{"application/json"},
+		order:       func(o *models.Order) ([]byte, error) { return encodeJSON(o) },
+		orders:      func(m map[string]*models.Order) ([]byte, error) { return encodeJSON(m) },
+	},
+	{
+		contentType: "text/csv; charset=utf-8",
+		mediaTypes:  []string{"text/csv"},
+		order:       func(o *models.Order) ([]byte, error) { return encodeCSV([]*models.Order{o}) },
+		orders:      func(m map[string]*models.Order) ([]byte, error) { return encodeCSV(sortedOrders(m)) },
+	},
+	{
+		contentType: "application/xml; charset=utf-8",
+		mediaTypes:  []string{"application/xml", "text/xml"},
+		order:       func(o *models.Order) ([]byte, error) { return encodeXML(o, "order") },
+		orders: func(m map[string]*models.Order) ([]byte, error) {
+			return encodeXML(struct {
+				Orders []*models.Order `xml:"order"`
+			}{sortedOrders(m)}, "orders")
+		},
+	},
+	{
+		contentType: "application/msgpack",
+		mediaTypes:  []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+		order:       func(o *models.Order) ([]byte, error) { return encodeMsgpack(o) },
+		orders:      func(m map[string]*models.Order) ([]byte, error) { return encodeMsgpack(m) },
+	},
+	{
+		contentType: "application/x-protobuf",
+		mediaTypes:  []string{"application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf"},
+		order:       func(o *models.Order) ([]byte, error) { return proto.Marshal(orderpb.FromModel(o)) },
+		orders: func(m map[string]*models.Order) ([]byte, error) {
+			return proto.MarshalOptions{Deterministic: true}.Marshal(orderpb.FromModels(sortedOrders(m)))
+		},
+	},
+}
+
+// negotiateFormat выбирает представление по Accept с учетом q-значений и
+// специфичности: type/subtype важнее type/*, а тот - */*. Пустой Accept - JSON.
+func negotiateFormat(header string) (format, bool) {
+	if strings.TrimSpace(header) == "" {
+		return formats[0], true
+	}
+
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []accepted
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, accepted{mediaType, q})
+	}
+
+	best, bestQ := -1, 0.0
+	for i, f := range formats {
+		// q берется из самого специфичного диапазона, под который подходит формат
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			for _, mediaType := range f.mediaTypes {
+				s := matchMediaRange(r.mediaType, mediaType)
+				if s > specificity || (s == specificity && r.q > q) {
+					q, specificity = r.q, s
+				}
+			}
+		}
+		if specificity >= 0 && q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	if best < 0 {
+		return format{}, false
+	}
+	return formats[best], true
+}
+
+// matchMediaRange возвращает специфичность совпадения mediaType с диапазоном из Accept
+// (2 - точное, 1 - type/*, 0 - */*) или -1, если не подходит.
+func matchMediaRange(mediaRange, mediaType string) int {
+	if mediaRange == mediaType {
+		return 2
+	}
+	if mediaRange == "*/*" {
+		return 0
+	}
+	if prefix, ok := strings.CutSuffix(mediaRange, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+		return 1
+	}
+	return -1
+}
+
+// negotiate выбирает формат ответа или отвечает 406.
+func negotiate(w http.ResponseWriter, r *http.Request) (format, bool) {
+	w.Header().Add("Vary", "Accept")
+	f, ok := negotiateFormat(r.Header.Get("Accept"))
+	if !ok {
+		supported := make([]string, len(formats))
+		for i, f := range formats {
+			supported[i] = f.mediaTypes[0]
+		}
+		http.Error(w, "Not acceptable, supported: "+strings.Join(supported, ", "), http.StatusNotAcceptable)
+	}
+	return f, ok
+}
+
+func sortedOrders(m map[string]*models.Order) []*models.Order {
+	orders := make([]*models.Order, 0, len(m))
+	for _, order := range m {
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderUID < orders[j].OrderUID })
+	return orders
+}
+
+func encodeJSON(v any) ([]byte, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
+
+func encodeXML(v any, root string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: root}}); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// encodeMsgpack кодирует по json-тегам, чтобы имена полей совпадали с JSON.
+func encodeMsgpack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	// Иначе порядок ключей случаен и ETag меняется от запроса к запросу
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var csvHeader = []string{
+	"order_uid", "track_number", "entry", "customer_id", "delivery_service", "locale",
+	"internal_signature", "shardkey", "sm_id", "oof_shard", "date_created",
+	"status", "fulfillment_status", "version",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address", "delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider", "payment_amount",
+	"payment_dt", "payment_bank", "payment_delivery_cost", "payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale", "item_size",
+	"item_total_price", "item_nm_id", "item_brand", "item_status", "item_quantity",
+}
+
+// encodeCSV выгружает по строке на позицию; поля заказа повторяются в каждой строке.
+// Заказ без позиций дает одну строку с пустыми колонками item_*.
+func encodeCSV(orders []*models.Order) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(csvHeader)
+
+	itoa := strconv.Itoa
+	for _, o := range orders {
+		row := []string{
+			o.OrderUID, o.TrackNumber, o.Entry, o.CustomerID, o.DeliveryService, o.Locale,
+			o.InternalSignature, o.Shardkey, itoa(o.SmID), o.OofShard, o.DateCreated.UTC().Format(time.RFC3339),
+			o.Status.String(), string(o.Fulfillment), strconv.FormatInt(o.Version, 10),
+			o.Delivery.Name, o.Delivery.Phone, o.Delivery.Zip, o.Delivery.City, o.Delivery.Address, o.Delivery.Region, o.Delivery.Email,
+			o.Payment.Transaction, o.Payment.RequestID, o.Payment.Currency, o.Payment.Provider, itoa(o.Payment.Amount),
+			strconv.FormatInt(o.Payment.PaymentDt, 10), o.Payment.Bank, itoa(o.Payment.DeliveryCost), itoa(o.Payment.GoodsTotal), itoa(o.Payment.CustomFee),
+		}
+		if len(o.Items) == 0 {
+			w.Write(append(row, make([]string, len(csvHeader)-len(row))...))
+			continue
+		}
+		for _, item := range o.Items {
+			w.Write(append(row[:len(row):len(row)],
+				itoa(item.ChrtID), item.TrackNumber, itoa(item.Price), item.Rid, item.Name, itoa(item.Sale), item.Size,
+				itoa(item.TotalPrice), itoa(item.NmID), item.Brand, string(item.Status), itoa(item.Quantity),
+			))
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
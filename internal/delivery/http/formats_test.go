@@ -0,0 +1,195 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/cache"
+	"order-service/internal/models"
+	"order-service/internal/orderpb"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/json", "application/json"},
+		{"text/csv", "text/csv"},
+		{"text/*", "text/csv"},
+		{"application/xml", "application/xml"},
+		{"text/xml", "application/xml"},
+		{"application/x-msgpack", "application/msgpack"},
+		{"application/protobuf", "application/x-protobuf"},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", "application/xml"},
+		{"application/json;q=0.5, text/csv", "text/csv"},
+		{"*/*, application/json;q=0", "text/csv"},
+		{"application/msgpack; charset=utf-8", "application/msgpack"},
+		{"text/html", ""},
+		{"application/json;q=0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			f, ok := negotiateFormat(tt.accept)
+			got := ""
+			if ok {
+				got = f.mediaTypes[0]
+			}
+			if got != tt.want {
+				t.Errorf("negotiateFormat(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_Formats(t *testing.T) {
+	cache := cache.New()
+	handler := NewHandler(cache, nil)
+	order := &models.Order{
+		OrderUID:    "test-123",
+		TrackNumber: "TRACK-1",
+		CustomerID:  "customer, \"quoted\"",
+		DateCreated: time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC),
+		Status:      models.StatusShipped,
+		Version:     3,
+		Payment:     models.Payment{Amount: 1817, Currency: "USD"},
+		Items: []models.Item{
+			{ChrtID: 1, Name: "Mascaras", Price: 453, Status: models.ItemShipped, Quantity: 1},
+			{ChrtID: 2, Name: "Lipstick", Price: 100, Status: models.ItemPending, Quantity: 2},
+		},
+		ContentHash: "secret-hash",
+	}
+	cache.Set(order)
+
+	get := func(target, accept string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest("GET", target, nil), map[string]string{"id": "test-123"})
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		if target == "/orders" {
+			handler.GetOrders(rr, req)
+		} else {
+			handler.GetOrder(rr, req)
+		}
+		return rr
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		for _, target := range []string{"/orders/test-123", "/orders"} {
+			rr := get(target, "text/csv")
+			if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+				t.Fatalf("%s: Content-Type = %q", target, ct)
+			}
+			rows, err := csv.NewReader(rr.Body).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Заголовок и по строке на позицию
+			if len(rows) != 3 {
+				t.Fatalf("%s: got %d rows, want 3", target, len(rows))
+			}
+			row := map[string]string{}
+			for i, column := range rows[0] {
+				row[column] = rows[2][i]
+			}
+			if row["order_uid"] != "test-123" || row["customer_id"] != order.CustomerID || row["status"] != "shipped" ||
+				row["item_name"] != "Lipstick" || row["item_quantity"] != "2" || row["payment_amount"] != "1817" {
+				t.Errorf("%s: unexpected row %v", target, row)
+			}
+		}
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		rr := get("/orders/test-123", "application/xml")
+		var got struct {
+			XMLName xml.Name `xml:"order"`
+			models.Order
+		}
+		if err := xml.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.OrderUID != "test-123" || len(got.Items) != 2 || got.Items[1].Name != "Lipstick" || got.Status != models.StatusShipped {
+			t.Errorf("unexpected order %+v", got.Order)
+		}
+		if strings.Contains(rr.Body.String(), "secret-hash") {
+			t.Error("XML must not expose internal fields")
+		}
+
+		rr = get("/orders", "application/xml")
+		var list struct {
+			Orders []models.Order `xml:"order"`
+		}
+		if err := xml.Unmarshal(rr.Body.Bytes(), &list); err != nil || len(list.Orders) != 1 {
+			t.Fatalf("orders: %v, %d orders", err, len(list.Orders))
+		}
+	})
+
+	t.Run("MessagePack", func(t *testing.T) {
+		rr := get("/orders/test-123", "application/msgpack")
+		if ct := rr.Header().Get("Content-Type"); ct != "application/msgpack" {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		// Ключи совпадают с JSON
+		var got map[string]any
+		if err := msgpack.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["order_uid"] != "test-123" || got["track_number"] != "TRACK-1" {
+			t.Errorf("unexpected order %v", got)
+		}
+		if _, ok := got["ContentHash"]; ok {
+			t.Error("MessagePack must not expose internal fields")
+		}
+	})
+
+	t.Run("Protobuf", func(t *testing.T) {
+		rr := get("/orders/test-123", "application/x-protobuf")
+		var got orderpb.Order
+		if err := proto.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.GetOrderUid() != "test-123" || got.GetStatus() != orderpb.OrderStatus_ORDER_STATUS_SHIPPED ||
+			len(got.GetItems()) != 2 || !got.GetDateCreated().AsTime().Equal(order.DateCreated) {
+			t.Errorf("unexpected order %v", &got)
+		}
+
+		rr = get("/orders", "application/x-protobuf")
+		var list orderpb.OrderList
+		if err := proto.Unmarshal(rr.Body.Bytes(), &list); err != nil || len(list.GetOrders()) != 1 {
+			t.Fatalf("orders: %v, %d orders", err, len(list.GetOrders()))
+		}
+	})
+
+	t.Run("Not acceptable", func(t *testing.T) {
+		rr := get("/orders/test-123", "text/html")
+		if rr.Code != http.StatusNotAcceptable {
+			t.Fatalf("status = %d, want 406", rr.Code)
+		}
+	})
+
+	t.Run("Representations differ", func(t *testing.T) {
+		jsonResp := get("/orders/test-123", "application/json")
+		csvResp := get("/orders/test-123", "text/csv")
+		if jsonResp.Header().Get("ETag") == csvResp.Header().Get("ETag") {
+			t.Error("JSON and CSV share an ETag")
+		}
+		if vary := jsonResp.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Accept" {
+			t.Errorf("Vary = %v, want Accept", vary)
+		}
+		var decoded models.Order
+		if err := json.Unmarshal(jsonResp.Body.Bytes(), &decoded); err != nil || decoded.OrderUID != "test-123" {
+			t.Errorf("JSON response: %v", err)
+		}
+	})
+}
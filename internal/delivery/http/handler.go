@@ -1,62 +1,155 @@
 package http
 
 import (
-	"compress/gzip"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"order-service/internal/cache"
-	"strings"
+	"order-service/internal/models"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// HeaderCacheTruncated выставляется в ответе GET /orders, когда кэш вытеснял заказы по лимиту.
+const HeaderCacheTruncated = "X-Cache-Truncated"
+
+// OrderReader - источник заказов на случай промаха кэша (например, после вытеснения).
+type OrderReader interface {
+	GetOrder(uid string) (*models.Order, error)
+	GetStatusHistory(ctx context.Context, uid string) ([]models.StatusChange, error)
+}
+
 type Handler struct {
 	cache *cache.Cache
+	repo  OrderReader
 }
 
-func NewHandler(cache *cache.Cache) *Handler {
-	return &Handler{cache: cache}
+func NewHandler(cache *cache.Cache, repo OrderReader) *Handler {
+	return &Handler{cache: cache, repo: repo}
 }
 
 func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	orderUID := vars["id"]
 
-	order, exists := h.cache.Get(orderUID)
-	if !exists {
+	f, ok := negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.lookup(orderUID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading order", "order_uid", orderUID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if order == nil {
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
 
-	// Optimization
-	w.Header().Set("Cache-Control", "public, max-age=120")
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	body, err := f.order(order)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding order", "order_uid", orderUID, "format", f.mediaTypes[0], "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	serveBody(w, r, f.contentType, body, order.UpdatedAt)
+}
 
-	// Optimization
-	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		json.NewEncoder(gz).Encode(order)
-	} else {
-		json.NewEncoder(w).Encode(order)
+// lookup ищет заказ в кэше, а при промахе - в репозитории. Возвращает nil, nil, если заказа нет.
+func (h *Handler) lookup(uid string) (*models.Order, error) {
+	if order, exists := h.cache.Get(uid); exists {
+		return order, nil
 	}
+	if h.repo == nil {
+		return nil, nil
+	}
+
+	order, err := h.repo.GetOrder(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h.cache.Set(order)
+	return order, nil
 }
 
 func (h *Handler) GetOrders(w http.ResponseWriter, r *http.Request) {
+	f, ok := negotiate(w, r)
+	if !ok {
+		return
+	}
+	// Время берется до снимка: изменение между ними даст лишний ответ 200, а не пропущенный
+	modified := h.cache.Modified()
 	orders := h.cache.GetAll()
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	body, err := f.orders(orders)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error encoding orders", "format", f.mediaTypes[0], "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// При cache.max_orders список может быть неполным: полный список - ListOrders в gRPC
+	if h.cache.Truncated() {
+		w.Header().Set(HeaderCacheTruncated, "true")
+	}
+	serveBody(w, r, f.contentType, body, modified)
+}
+
+type statusChangeView struct {
+	From      models.OrderStatus `json:"from_status"`
+	FromName  string             `json:"from_status_name"`
+	To        models.OrderStatus `json:"to_status"`
+	ToName    string             `json:"to_status_name"`
+	ChangedAt time.Time          `json:"changed_at"`
+}
+
+func (h *Handler) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	orderUID := mux.Vars(r)["id"]
 
-	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		json.NewEncoder(gz).Encode(orders)
-	} else {
-		json.NewEncoder(w).Encode(orders)
+	order, err := h.lookup(orderUID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading order", "order_uid", orderUID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if order == nil {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
 	}
+
+	history := []statusChangeView{}
+	if h.repo != nil {
+		changes, err := h.repo.GetStatusHistory(r.Context(), orderUID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error loading status history", "order_uid", orderUID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		for _, c := range changes {
+			history = append(history, statusChangeView{
+				From: c.From, FromName: c.From.String(),
+				To: c.To, ToName: c.To.String(),
+				ChangedAt: c.ChangedAt,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"order_uid":   order.OrderUID,
+		"status":      order.Status,
+		"status_name": order.Status.String(),
+		"history":     history,
+	})
 }
 
 func (h *Handler) ServeOrderPage(w http.ResponseWriter, r *http.Request) {
@@ -1,11 +1,14 @@
 package http
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"order-service/internal/cache"
 	"order-service/internal/models"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,7 +17,7 @@ import (
 
 func TestHandler_GetOrder(t *testing.T) {
 	cache := cache.New()
-	handler := NewHandler(cache)
+	handler := NewHandler(cache, nil)
 
 	// Добавляем тестовый заказ в кэш
 	order := &models.Order{
@@ -55,9 +58,93 @@ func TestHandler_GetOrder(t *testing.T) {
 	}
 }
 
+func TestHandler_ConditionalGet(t *testing.T) {
+	cache := cache.New()
+	handler := NewHandler(cache, nil)
+	updated := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+	cache.Set(&models.Order{OrderUID: "test-123", TrackNumber: "TRACK-1", Version: 1, UpdatedAt: updated})
+
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest("GET", target, nil), map[string]string{"id": "test-123"})
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		if target == "/orders" {
+			handler.GetOrders(rr, req)
+		} else {
+			handler.GetOrder(rr, req)
+		}
+		return rr
+	}
+
+	for _, target := range []string{"/orders/test-123", "/orders"} {
+		t.Run(target, func(t *testing.T) {
+			first := get(target, nil)
+			etag := first.Header().Get("ETag")
+			if first.Code != http.StatusOK || etag == "" || strings.HasPrefix(etag, "W/") {
+				t.Fatalf("status %d, ETag %q", first.Code, etag)
+			}
+			if rr := get(target, map[string]string{"If-None-Match": etag}); rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+				t.Errorf("If-None-Match: status %d, body %d bytes", rr.Code, rr.Body.Len())
+			}
+
+			// У списка Last-Modified - время последнего изменения кэша, а не заказа
+			modified := updated
+			if target == "/orders" {
+				modified = cache.Modified()
+			}
+			if lm := first.Header().Get("Last-Modified"); lm != modified.UTC().Format(http.TimeFormat) {
+				t.Errorf("Last-Modified = %q", lm)
+			}
+			if rr := get(target, map[string]string{"If-Modified-Since": modified.UTC().Format(http.TimeFormat)}); rr.Code != http.StatusNotModified {
+				t.Errorf("If-Modified-Since: status %d", rr.Code)
+			}
+			if rr := get(target, map[string]string{"If-Modified-Since": modified.Add(-time.Hour).UTC().Format(http.TimeFormat)}); rr.Code != http.StatusOK {
+				t.Errorf("stale If-Modified-Since: status %d", rr.Code)
+			}
+		})
+	}
+
+	// Новая ревизия меняет ETag
+	before := get("/orders/test-123", nil).Header().Get("ETag")
+	cache.Set(&models.Order{OrderUID: "test-123", TrackNumber: "TRACK-2", Version: 2, UpdatedAt: updated.Add(time.Minute)})
+	rr := get("/orders/test-123", map[string]string{"If-None-Match": before})
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") == before {
+		t.Errorf("Expected a fresh response after update, got %d with ETag %s", rr.Code, rr.Header().Get("ETag"))
+	}
+}
+
+func TestHandler_GetOrdersTruncated(t *testing.T) {
+	cache := cache.New()
+	handler := NewHandler(cache, nil)
+	cache.Set(&models.Order{OrderUID: "order-1", Version: 1})
+	cache.Set(&models.Order{OrderUID: "order-2", Version: 1})
+
+	get := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.GetOrders(rr, httptest.NewRequest("GET", "/orders", nil))
+		return rr
+	}
+
+	if rr := get(); rr.Header().Get(HeaderCacheTruncated) != "" {
+		t.Errorf("Full cache must not be marked truncated, got %q", rr.Header().Get(HeaderCacheTruncated))
+	}
+
+	cache.SetLimit(1)
+	rr := get()
+	if rr.Code != http.StatusOK || rr.Header().Get(HeaderCacheTruncated) != "true" {
+		t.Errorf("After eviction: status %d, %s %q", rr.Code, HeaderCacheTruncated, rr.Header().Get(HeaderCacheTruncated))
+	}
+	var orders map[string]*models.Order
+	if err := json.Unmarshal(rr.Body.Bytes(), &orders); err != nil || len(orders) != 1 {
+		t.Errorf("Expected 1 cached order, got %d (%v)", len(orders), err)
+	}
+}
+
 func TestHandler_GetOrderNotFound(t *testing.T) {
 	cache := cache.New()
-	handler := NewHandler(cache)
+	handler := NewHandler(cache, nil)
 
 	req, err := http.NewRequest("GET", "/orders/non-existent", nil)
 	if err != nil {
@@ -73,3 +160,74 @@ func TestHandler_GetOrderNotFound(t *testing.T) {
 		t.Errorf("Expected status 404, got %d", status)
 	}
 }
+
+type fakeRepo struct {
+	orders  map[string]*models.Order
+	history map[string][]models.StatusChange
+}
+
+func (r *fakeRepo) GetOrder(uid string) (*models.Order, error) {
+	order, ok := r.orders[uid]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return order, nil
+}
+
+func (r *fakeRepo) GetStatusHistory(ctx context.Context, uid string) ([]models.StatusChange, error) {
+	return r.history[uid], nil
+}
+
+func TestHandler_GetStatusHistory(t *testing.T) {
+	changedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &fakeRepo{
+		orders: map[string]*models.Order{
+			"test-123": {OrderUID: "test-123", Status: models.StatusPaid},
+		},
+		history: map[string][]models.StatusChange{
+			"test-123": {
+				{From: models.StatusUnknown, To: models.StatusCreated, ChangedAt: changedAt},
+				{From: models.StatusCreated, To: models.StatusPaid, ChangedAt: changedAt.Add(time.Hour)},
+			},
+		},
+	}
+	handler := NewHandler(cache.New(), repo)
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/orders/test-123/status-history", nil),
+		map[string]string{"id": "test-123"})
+	rr := httptest.NewRecorder()
+	handler.GetStatusHistory(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var body struct {
+		StatusName string `json:"status_name"`
+		History    []struct {
+			ToName string `json:"to_status_name"`
+		} `json:"history"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if body.StatusName != "paid" {
+		t.Errorf("Expected status paid, got %s", body.StatusName)
+	}
+	if len(body.History) != 2 || body.History[1].ToName != "paid" {
+		t.Errorf("Unexpected history: %+v", body.History)
+	}
+}
+
+func TestHandler_GetStatusHistoryNotFound(t *testing.T) {
+	handler := NewHandler(cache.New(), &fakeRepo{})
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/orders/missing/status-history", nil),
+		map[string]string{"id": "missing"})
+	rr := httptest.NewRecorder()
+	handler.GetStatusHistory(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rr.Code)
+	}
+}
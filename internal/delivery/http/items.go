@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"order-service/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+type ItemStatusUpdater interface {
+	UpdateItemStatus(ctx context.Context, uid, rid string, status models.ItemStatus) (*models.Order, error)
+}
+
+type ItemHandler struct {
+	orders ItemStatusUpdater
+}
+
+func NewItemHandler(orders ItemStatusUpdater) *ItemHandler {
+	return &ItemHandler{orders: orders}
+}
+
+// UpdateStatus - PATCH /orders/{id}/items/{rid}/status с телом {"status": "shipped"}
+// (допускается и числовой код старых продюсеров).
+func (h *ItemHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var body struct {
+		Status *models.ItemStatus `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Status == nil {
+		http.Error(w, "status is required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orders.UpdateItemStatus(r.Context(), vars["id"], vars["rid"], *body.Status)
+	switch {
+	case errors.Is(err, models.ErrOrderNotFound):
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	case errors.Is(err, models.ErrItemNotFound):
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Error updating item status", "order_uid", vars["id"], "rid", vars["rid"], "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(order)
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Клиенты без запросов дольше limiterIdle забываются
+const limiterIdle = 3 * time.Minute
+
+// RateLimiter ограничивает число запросов в секунду отдельно для каждого IP клиента,
+// чтобы один клиент не исчерпал лимит остальных. Лимит можно менять на лету.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{limit: rate.Inf, clients: make(map[string]*clientLimiter)}
+}
+
+// SetLimit задает лимит на клиента; rps <= 0 отключает ограничение.
+func (rl *RateLimiter) SetLimit(rps float64, burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rps <= 0 {
+		rl.limit, rl.burst = rate.Inf, 0
+	} else {
+		if burst <= 0 {
+			burst = int(rps) + 1
+		}
+		rl.limit, rl.burst = rate.Limit(rps), burst
+	}
+	for _, c := range rl.clients {
+		c.limiter.SetLimit(rl.limit)
+		c.limiter.SetBurst(rl.burst)
+	}
+}
+
+func (rl *RateLimiter) allow(key string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.limit == rate.Inf {
+		return true
+	}
+	now := time.Now()
+	if now.Sub(rl.lastSweep) > limiterIdle {
+		for k, c := range rl.clients {
+			if now.Sub(c.seen) > limiterIdle {
+				delete(rl.clients, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	c, ok := rl.clients[key]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.clients[key] = c
+	}
+	c.seen = now
+	return c.limiter.AllowN(now, 1)
+}
+
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !rl.allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP - адрес соединения. X-Forwarded-For не учитывается: его подделывает любой клиент.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
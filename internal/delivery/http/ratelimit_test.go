@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimiter_PerClient(t *testing.T) {
+	rl := NewRateLimiter()
+	rl.SetLimit(1, 2)
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	get := func(addr string) int {
+		req := httptest.NewRequest("GET", "/orders", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := get("10.0.0.1:5000"); code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, code)
+		}
+	}
+	// Другой порт того же клиента делит с ним лимит
+	if code := get("10.0.0.1:5001"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after burst, got %d", code)
+	}
+	if code := get("10.0.0.2:5000"); code != http.StatusOK {
+		t.Errorf("Expected another client to be unaffected, got %d", code)
+	}
+
+	rl.SetLimit(0, 0)
+	if code := get("10.0.0.1:5000"); code != http.StatusOK {
+		t.Errorf("Expected no limit after disabling, got %d", code)
+	}
+}
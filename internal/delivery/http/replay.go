@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"order-service/internal/broker"
+	"order-service/internal/service"
+	"time"
+)
+
+type ReplayRunner interface {
+	Run(ctx context.Context, opts service.ReplayOptions) (*service.ReplayReport, error)
+}
+
+type ReplayHandler struct {
+	replayer ReplayRunner
+}
+
+// NewReplayHandler принимает nil, если брокер не умеет перечитывать историю.
+func NewReplayHandler(replayer ReplayRunner) *ReplayHandler {
+	return &ReplayHandler{replayer: replayer}
+}
+
+// Replay - POST /admin/replay с телом
+// {"from_sequence": 120} или {"from_time": "2025-10-19T10:00:00Z"}, плюс "apply", "limit", "idle_timeout".
+// Без "apply": true изменения только оцениваются (dry-run).
+func (h *ReplayHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	if h.replayer == nil {
+		http.Error(w, "Replay is not supported by the configured broker", http.StatusNotImplemented)
+		return
+	}
+
+	var body struct {
+		FromSequence uint64    `json:"from_sequence"`
+		FromTime     time.Time `json:"from_time"`
+		Apply        bool      `json:"apply"`
+		Limit        int       `json:"limit"`
+		IdleTimeout  string    `json:"idle_timeout"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (body.FromSequence == 0) == body.FromTime.IsZero() {
+		http.Error(w, "exactly one of from_sequence or from_time is required", http.StatusBadRequest)
+		return
+	}
+	if body.Limit < 0 {
+		http.Error(w, "limit must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	opts := service.ReplayOptions{
+		From:  broker.Position{Sequence: body.FromSequence, Time: body.FromTime},
+		Apply: body.Apply,
+		Limit: body.Limit,
+	}
+	if body.IdleTimeout != "" {
+		d, err := time.ParseDuration(body.IdleTimeout)
+		if err != nil || d <= 0 {
+			http.Error(w, "idle_timeout must be a positive duration like 2s", http.StatusBadRequest)
+			return
+		}
+		opts.IdleTimeout = d
+	}
+
+	report, err := h.replayer.Run(r.Context(), opts)
+	switch {
+	case errors.Is(err, service.ErrReplayRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil && report == nil:
+		slog.ErrorContext(r.Context(), "Replay failed", "error", err)
+		http.Error(w, "Replay failed: "+err.Error(), http.StatusBadGateway)
+		return
+	case err != nil:
+		// Запрос отменен - отдаем то, что успели обработать
+		slog.WarnContext(r.Context(), "Replay interrupted", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(report)
+}
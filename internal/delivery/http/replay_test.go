@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeReplayer struct {
+	opts service.ReplayOptions
+	err  error
+}
+
+func (f *fakeReplayer) Run(ctx context.Context, opts service.ReplayOptions) (*service.ReplayReport, error) {
+	f.opts = opts
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &service.ReplayReport{Apply: opts.Apply, Messages: 2, Created: 1, Duplicates: 1}, nil
+}
+
+func TestReplayHandler_Replay(t *testing.T) {
+	tests := []struct {
+		name     string
+		replayer ReplayRunner
+		body     string
+		wantCode int
+	}{
+		{"sequence", &fakeReplayer{}, `{"from_sequence": 5, "limit": 10, "idle_timeout": "500ms"}`, http.StatusOK},
+		{"time", &fakeReplayer{}, `{"from_time": "2025-10-19T10:00:00Z", "apply": true}`, http.StatusOK},
+		{"no position", &fakeReplayer{}, `{"apply": true}`, http.StatusBadRequest},
+		{"both positions", &fakeReplayer{}, `{"from_sequence": 1, "from_time": "2025-10-19T10:00:00Z"}`, http.StatusBadRequest},
+		{"bad timeout", &fakeReplayer{}, `{"from_sequence": 1, "idle_timeout": "soon"}`, http.StatusBadRequest},
+		{"invalid json", &fakeReplayer{}, `{`, http.StatusBadRequest},
+		{"already running", &fakeReplayer{err: service.ErrReplayRunning}, `{"from_sequence": 1}`, http.StatusConflict},
+		{"unsupported", nil, `{"from_sequence": 1}`, http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/admin/replay", strings.NewReader(tt.body))
+			NewReplayHandler(tt.replayer).Replay(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var report service.ReplayReport
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("decode report: %v", err)
+			}
+			if report.Messages != 2 || report.Created != 1 {
+				t.Errorf("report = %+v", report)
+			}
+		})
+	}
+
+	f := &fakeReplayer{}
+	req := httptest.NewRequest("POST", "/admin/replay", strings.NewReader(`{"from_sequence": 7, "limit": 3, "idle_timeout": "1s"}`))
+	NewReplayHandler(f).Replay(httptest.NewRecorder(), req)
+	if f.opts.From.Sequence != 7 || f.opts.Limit != 3 || f.opts.IdleTimeout != time.Second || f.opts.Apply {
+		t.Errorf("options = %+v", f.opts)
+	}
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"order-service/internal/models"
+	"order-service/internal/stream"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StreamHandler struct {
+	hub       *stream.Hub
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(hub *stream.Hub) *StreamHandler {
+	return &StreamHandler{hub: hub, heartbeat: 15 * time.Second}
+}
+
+// Orders - GET /orders/stream?customer_id=...&status=paid,shipped&order_uid=...
+// Server-Sent Events: событие "order" с заказом на каждое сохранение. Переподключение
+// с Last-Event-ID (или ?last_event_id=) досылает пропущенное из буфера; если буфер
+// уже вытеснил часть изменений, сначала приходит событие "reset".
+func (h *StreamHandler) Orders(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := stream.Filter{CustomerID: query.Get("customer_id"), OrderUID: query.Get("order_uid")}
+	if raw := query.Get("status"); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			status, err := models.ParseOrderStatus(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	sub, backlog, complete := h.hub.Subscribe(filter, lastID, lastEventID != "")
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Отключает буферизацию в nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, update := range backlog {
+		if err := writeUpdate(w, update); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case update, ok := <-sub.Updates():
+			if !ok {
+				slog.WarnContext(r.Context(), "Order stream client is too slow, disconnecting")
+				return
+			}
+			if err := writeUpdate(w, update); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeUpdate(w http.ResponseWriter, update stream.Update) error {
+	data, err := json.Marshal(update.Order)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: order\ndata: %s\n\n", update.ID, data)
+	return err
+}
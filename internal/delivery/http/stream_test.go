@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/models"
+	"order-service/internal/stream"
+	"strings"
+	"testing"
+)
+
+// readEvent читает одно SSE-событие и возвращает его поля; комментарии пропускаются.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	event := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(event) > 0 {
+				return event
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		event[name] = value
+	}
+}
+
+func TestStreamHandler_Orders(t *testing.T) {
+	hub := stream.NewHub(16, 16)
+	handler := NewStreamHandler(hub)
+	// Через middleware: statusRecorder должен пропускать Flush
+	server := httptest.NewServer(Tracing(Logging(http.HandlerFunc(handler.Orders))))
+	defer server.Close()
+
+	hub.Publish(&models.Order{OrderUID: "old", CustomerID: "alice", Status: models.StatusCreated})
+
+	resp, err := http.Get(server.URL + "?customer_id=alice&status=paid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	reader := bufio.NewReader(resp.Body)
+	if e := readEvent(t, reader); e["retry"] != "3000" {
+		t.Fatalf("first event = %v", e)
+	}
+
+	hub.Publish(&models.Order{OrderUID: "o1", CustomerID: "bob", Status: models.StatusPaid})
+	hub.Publish(&models.Order{OrderUID: "o2", CustomerID: "alice", Status: models.StatusPaid})
+	e := readEvent(t, reader)
+	if e["event"] != "order" || !strings.Contains(e["data"], `"order_uid":"o2"`) {
+		t.Fatalf("event = %v", e)
+	}
+	lastID := e["id"]
+
+	// Переподключение: пропущенное досылается из буфера
+	hub.Publish(&models.Order{OrderUID: "o3", CustomerID: "alice", Status: models.StatusPaid})
+	req, _ := http.NewRequest("GET", server.URL+"?customer_id=alice", nil)
+	req.Header.Set("Last-Event-ID", lastID)
+	resumed, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Body.Close()
+	reader = bufio.NewReader(resumed.Body)
+	readEvent(t, reader) // retry
+	if e := readEvent(t, reader); !strings.Contains(e["data"], `"order_uid":"o3"`) {
+		t.Errorf("resumed event = %v", e)
+	}
+
+	// Неизвестный ID - клиенту нужно перечитать состояние
+	req.Header.Set("Last-Event-ID", "1")
+	stale, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stale.Body.Close()
+	reader = bufio.NewReader(stale.Body)
+	readEvent(t, reader)
+	if e := readEvent(t, reader); e["event"] != "reset" {
+		t.Errorf("Expected reset event, got %v", e)
+	}
+}
+
+func TestStreamHandler_BadRequest(t *testing.T) {
+	handler := NewStreamHandler(stream.NewHub(1, 1))
+	for _, target := range []string{"/orders/stream?status=lost", "/orders/stream?last_event_id=abc"} {
+		rec := httptest.NewRecorder()
+		handler.Orders(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"order-service/internal/events"
+	"order-service/internal/webhook"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookRegistry interface {
+	Register(ctx context.Context, w *webhook.Webhook) error
+	Deliveries(ctx context.Context, webhookID string, limit int) ([]*webhook.Delivery, error)
+}
+
+type WebhookHandler struct {
+	webhooks WebhookRegistry
+}
+
+// NewWebhookHandler принимает nil, если вебхуки отключены.
+func NewWebhookHandler(webhooks WebhookRegistry) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// Create - POST /webhooks с телом {"url": "...", "events": ["order.created"], "secret": "..."}.
+// Без events вебхук получает все события.
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if h.webhooks == nil {
+		http.Error(w, "Webhooks are disabled", http.StatusNotImplemented)
+		return
+	}
+
+	var body struct {
+		URL    string        `json:"url"`
+		Events []events.Type `json:"events"`
+		Secret string        `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hook := &webhook.Webhook{URL: body.URL, Events: body.Events, Secret: body.Secret}
+	err := h.webhooks.Register(r.Context(), hook)
+	var invalid *webhook.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Error registering webhook", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(r.Context(), "Webhook registered", "webhook_id", hook.ID, "url", hook.URL, "events", hook.Events)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Location", "/webhooks/"+hook.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+// Deliveries - GET /webhooks/{id}/deliveries?limit=50: последние доставки с попытками.
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	if h.webhooks == nil {
+		http.Error(w, "Webhooks are disabled", http.StatusNotImplemented)
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	id := mux.Vars(r)["id"]
+	deliveries, err := h.webhooks.Deliveries(r.Context(), id, limit)
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Error loading webhook deliveries", "webhook_id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(deliveries)
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"order-service/internal/webhook"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type fakeRegistry struct {
+	hooks map[string]*webhook.Webhook
+}
+
+func (f *fakeRegistry) Register(ctx context.Context, w *webhook.Webhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	w.ID = "wh-1"
+	f.hooks[w.ID] = w
+	return nil
+}
+
+func (f *fakeRegistry) Deliveries(ctx context.Context, id string, limit int) ([]*webhook.Delivery, error) {
+	if f.hooks[id] == nil {
+		return nil, webhook.ErrNotFound
+	}
+	return []*webhook.Delivery{{ID: 1, WebhookID: id, Status: webhook.StatusSucceeded}}, nil
+}
+
+func TestWebhookHandler(t *testing.T) {
+	registry := &fakeRegistry{hooks: map[string]*webhook.Webhook{}}
+	h := NewWebhookHandler(registry)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Create(rec, httptest.NewRequest("POST", "/webhooks", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := create(`{"url":"https://partner.example/hook","events":["order.created"],"secret":"0123456789abcdef"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "0123456789abcdef") {
+		t.Error("secret must not be returned")
+	}
+	if rec.Header().Get("Location") != "/webhooks/wh-1" {
+		t.Errorf("Location = %q", rec.Header().Get("Location"))
+	}
+
+	for _, body := range []string{`{`, `{"url":"nope","secret":"0123456789abcdef"}`, `{"url":"https://partner.example","secret":"x"}`} {
+		if rec := create(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	deliveries := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/webhooks/"+id+"/deliveries", nil), map[string]string{"id": id})
+		h.Deliveries(rec, req)
+		return rec
+	}
+	rec = deliveries("wh-1")
+	var list []webhook.Delivery
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || rec.Code != http.StatusOK || len(list) != 1 {
+		t.Errorf("deliveries: status %d, %v, %+v", rec.Code, err, list)
+	}
+	if rec := deliveries("missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing webhook: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	NewWebhookHandler(nil).Create(rec, httptest.NewRequest("POST", "/webhooks", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("disabled: status = %d", rec.Code)
+	}
+}
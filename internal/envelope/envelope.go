@@ -0,0 +1,93 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// CurrentSchemaVersion - версия схемы models.Order, которую понимает сервис.
+	// v1 - исходный формат (голый JSON заказа, статусы 1=In Store, 2=In Transit, 3=Delivered),
+	// v2 - жизненный цикл заказа created → paid → assembling → shipped → delivered.
+	CurrentSchemaVersion = 2
+
+	ContentTypeJSON = "application/json"
+)
+
+// Envelope - конверт сообщения с заказом. Headers переносят контекст трассировки:
+// у NATS Streaming нет заголовков сообщений.
+type Envelope struct {
+	SchemaVersion int               `json:"schema_version"`
+	MessageID     string            `json:"message_id"`
+	Producer      string            `json:"producer"`
+	Timestamp     time.Time         `json:"timestamp"`
+	ContentType   string            `json:"content_type"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Payload       json.RawMessage   `json:"payload"`
+
+	// Legacy - сообщение пришло без конверта
+	Legacy bool `json:"-"`
+}
+
+// New оборачивает заказ текущей версии схемы в конверт с новым ID.
+func New(producer string, payload []byte) *Envelope {
+	return &Envelope{
+		SchemaVersion: CurrentSchemaVersion,
+		MessageID:     NewID(),
+		Producer:      producer,
+		Timestamp:     time.Now().UTC(),
+		ContentType:   ContentTypeJSON,
+		Headers:       map[string]string{},
+		Payload:       payload,
+	}
+}
+
+func (e *Envelope) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Parse разбирает сообщение. Сообщение без поля payload считается голым заказом
+// версии 1; конверт без schema_version (ранний формат с одними headers) - тоже версии 1.
+func Parse(data []byte) (*Envelope, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("message is not a JSON object: %v", err)
+	}
+
+	if _, ok := probe["payload"]; !ok {
+		return &Envelope{
+			SchemaVersion: 1,
+			ContentType:   ContentTypeJSON,
+			Payload:       bytes.TrimSpace(data),
+			Legacy:        true,
+		}, nil
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid envelope: %v", err)
+	}
+	if env.SchemaVersion == 0 {
+		env.SchemaVersion = 1
+	}
+	if env.ContentType == "" {
+		env.ContentType = ContentTypeJSON
+	}
+	if env.ContentType != ContentTypeJSON {
+		return nil, fmt.Errorf("unsupported content type %q", env.ContentType)
+	}
+	if len(env.Payload) == 0 || string(env.Payload) == "null" {
+		return nil, fmt.Errorf("envelope %s has empty payload", env.MessageID)
+	}
+	return &env, nil
+}
+
+func NewID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,138 @@
+package envelope
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version int
+		legacy  bool
+		wantErr bool
+	}{
+		{
+			name:    "голый заказ",
+			data:    `{"order_uid":"a","status":2}`,
+			version: 1,
+			legacy:  true,
+		},
+		{
+			name:    "конверт v2",
+			data:    `{"schema_version":2,"message_id":"m1","producer":"p","content_type":"application/json","payload":{"order_uid":"a"}}`,
+			version: 2,
+		},
+		{
+			name:    "конверт без версии",
+			data:    `{"headers":{"traceparent":"x"},"payload":{"order_uid":"a"}}`,
+			version: 1,
+		},
+		{
+			name:    "неподдерживаемый content type",
+			data:    `{"schema_version":2,"content_type":"application/xml","payload":"<order/>"}`,
+			wantErr: true,
+		},
+		{
+			name:    "пустой payload",
+			data:    `{"schema_version":2,"payload":null}`,
+			wantErr: true,
+		},
+		{
+			name:    "не JSON",
+			data:    `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if env.SchemaVersion != tt.version || env.Legacy != tt.legacy {
+				t.Errorf("Parse() version = %d legacy = %v, want %d %v", env.SchemaVersion, env.Legacy, tt.version, tt.legacy)
+			}
+		})
+	}
+}
+
+func TestNewRoundTrip(t *testing.T) {
+	env := New("test", []byte(`{"order_uid":"a"}`))
+	env.Headers["traceparent"] = "00-abc-def-01"
+	data, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MessageID != env.MessageID || got.Producer != "test" || got.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("Parse() = %+v", got)
+	}
+	if got.Headers["traceparent"] != "00-abc-def-01" {
+		t.Errorf("headers lost: %v", got.Headers)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		payload string
+		status  string
+		wantErr bool
+	}{
+		{name: "v1 In Store", version: 1, payload: `{"status":1}`, status: "1"},
+		{name: "v1 In Transit", version: 1, payload: `{"status":2}`, status: "4"},
+		{name: "v1 Delivered", version: 1, payload: `{"status":3}`, status: "5"},
+		{name: "v1 Processing", version: 1, payload: `{"status":4}`, status: "3"},
+		{name: "v1 In Store строкой", version: 1, payload: `{"status":"1"}`, status: `"1"`},
+		{name: "v1 In Transit строкой", version: 1, payload: `{"status":"2"}`, status: `"4"`},
+		{name: "v1 Delivered строкой", version: 1, payload: `{"status":"3"}`, status: `"5"`},
+		{name: "v1 Processing строкой", version: 1, payload: `{"status":"4"}`, status: `"3"`},
+		{name: "v1 статус именем", version: 1, payload: `{"status":"delivered"}`, status: `"delivered"`},
+		{name: "v1 без статуса", version: 1, payload: `{"order_uid":"a"}`, status: ""},
+		{name: "текущая версия не меняется", version: 2, payload: `{"status":2}`, status: "2"},
+		{name: "версия из будущего", version: 3, payload: `{}`, wantErr: true},
+		{name: "нулевая версия", version: 0, payload: `{}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Upgrade(tt.version, []byte(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Upgrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			dec := json.NewDecoder(strings.NewReader(string(out)))
+			dec.UseNumber()
+			var raw map[string]interface{}
+			if err := dec.Decode(&raw); err != nil {
+				t.Fatal(err)
+			}
+			// Строковый статус - в кавычках, чтобы отличить его от числа
+			got := ""
+			switch s := raw["status"].(type) {
+			case json.Number:
+				got = s.String()
+			case string:
+				got = strconv.Quote(s)
+			}
+			if got != tt.status {
+				t.Errorf("status = %q, want %q", got, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Upgrader переводит полезную нагрузку из версии N в версию N+1.
+type Upgrader func(payload map[string]interface{}) error
+
+// upgraders[N] переводит версию N в N+1.
+var upgraders = map[int]Upgrader{
+	1: upgradeV1,
+}
+
+// Upgrade последовательно применяет upgraders, пока версия не станет текущей.
+func Upgrade(version int, payload []byte) ([]byte, error) {
+	if version == CurrentSchemaVersion {
+		return payload, nil
+	}
+	if version < 1 || version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d (current is %d)", version, CurrentSchemaVersion)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var order map[string]interface{}
+	if err := dec.Decode(&order); err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+
+	for v := version; v < CurrentSchemaVersion; v++ {
+		upgrade, ok := upgraders[v]
+		if !ok {
+			return nil, fmt.Errorf("no upgrader from schema version %d", v)
+		}
+		if err := upgrade(order); err != nil {
+			return nil, fmt.Errorf("upgrade from schema version %d: %v", v, err)
+		}
+	}
+
+	return json.Marshal(order)
+}
+
+// legacyStatuses - коды статусов v1, сменившие номер в v2: 2 (In Transit) → 4 (shipped),
+// 3 (Delivered) → 5 (delivered), 4 (Processing) → 3 (assembling). 1 (In Store) остался created.
+// Та же перенумерация выполняется для сохраненных заказов в migrations/init.sql.
+var legacyStatuses = map[string]string{"2": "4", "3": "5", "4": "3"}
+
+// v1 → v2: перенумерация статусов. Статус строкой ("2") - тот же код, тип сохраняется,
+// чтобы декодер предупредил о приведении как обычно.
+func upgradeV1(order map[string]interface{}) error {
+	switch status := order["status"].(type) {
+	case json.Number:
+		if code, ok := legacyStatuses[status.String()]; ok {
+			order["status"] = json.Number(code)
+		}
+	case string:
+		if code, ok := legacyStatuses[strings.TrimSpace(status)]; ok {
+			order["status"] = code
+		}
+	}
+	return nil
+}
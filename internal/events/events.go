@@ -0,0 +1,163 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"order-service/internal/envelope"
+	"order-service/internal/models"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Type - тип доменного события. Схема каждого типа лежит в schemas/<type>.json.
+type Type string
+
+const (
+	OrderCreated       Type = "order.created"
+	OrderUpdated       Type = "order.updated"
+	OrderStatusChanged Type = "order.status_changed"
+	OrderRejected      Type = "order.rejected"
+)
+
+var Types = []Type{OrderCreated, OrderUpdated, OrderStatusChanged, OrderRejected}
+
+// Event - доменное событие заказа. Поля без omitempty есть у всех типов,
+// остальные заполняются в зависимости от типа (см. схемы).
+type Event struct {
+	ID       string `json:"id"`
+	Type     Type   `json:"type"`
+	OrderUID string `json:"order_uid"`
+	// Revision - версия заказа после изменения (0 - продюсер не передает версии)
+	Revision       int64              `json:"revision"`
+	Status         models.OrderStatus `json:"status,omitempty"`
+	PreviousStatus models.OrderStatus `json:"previous_status,omitempty"`
+	ChangedFields  []string           `json:"changed_fields,omitempty"`
+	Reason         string             `json:"reason,omitempty"`
+	TraceID        string             `json:"trace_id,omitempty"`
+	OccurredAt     time.Time          `json:"occurred_at"`
+}
+
+// New создает событие с новым ID и trace ID из контекста.
+func New(ctx context.Context, typ Type, orderUID string) Event {
+	e := Event{
+		ID:         envelope.NewID(),
+		Type:       typ,
+		OrderUID:   orderUID,
+		OccurredAt: time.Now().UTC(),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		e.TraceID = sc.TraceID().String()
+	}
+	return e
+}
+
+// Committed строит событие о сохраненном заказе: created для нового,
+// status_changed при смене статуса, иначе updated.
+func Committed(ctx context.Context, order *models.Order, found bool, previous models.OrderStatus, changed []string) Event {
+	typ := OrderCreated
+	switch {
+	case found && order.Status != previous:
+		typ = OrderStatusChanged
+	case found:
+		typ = OrderUpdated
+	}
+	e := New(ctx, typ, order.OrderUID)
+	e.Revision = order.Version
+	e.Status = order.Status
+	e.ChangedFields = changed
+	if typ == OrderStatusChanged {
+		e.PreviousStatus = previous
+	}
+	return e
+}
+
+// Rejected строит событие об отклоненном сообщении.
+func Rejected(ctx context.Context, orderUID string, revision int64, reason error) Event {
+	e := New(ctx, OrderRejected, orderUID)
+	e.Revision = revision
+	e.Reason = reason.Error()
+	return e
+}
+
+// Publisher отправляет событие в subject брокера: stan.Conn для NATS Streaming,
+// broker.JetStreamPublisher (с подтверждением потока) для JetStream и webhook.Dispatcher.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Subjects - subject брокера для каждого типа события.
+type Subjects struct {
+	Created       string
+	Updated       string
+	StatusChanged string
+	Rejected      string
+}
+
+func (s Subjects) For(typ Type) string {
+	switch typ {
+	case OrderCreated:
+		return s.Created
+	case OrderUpdated:
+		return s.Updated
+	case OrderStatusChanged:
+		return s.StatusChanged
+	case OrderRejected:
+		return s.Rejected
+	}
+	return ""
+}
+
+// Message - событие, готовое к записи в outbox и публикации.
+type Message struct {
+	EventID string
+	Subject string
+	Data    []byte
+}
+
+// OutboxFunc строит сообщения outbox для изменения, которое хранилище решает внутри
+// транзакции: current - состояние заказа после записи, previous - статус до нее.
+type OutboxFunc func(orderUID string, current models.OrderState, previous models.OrderStatus) []Message
+
+// Emitter превращает события в сообщения outbox. Сообщения записываются в БД
+// в одной транзакции с заказом, а публикует их Relay.
+type Emitter struct {
+	subjects Subjects
+}
+
+func NewEmitter(subjects Subjects) *Emitter {
+	return &Emitter{subjects: subjects}
+}
+
+func (e *Emitter) Encode(event Event) (Message, error) {
+	subject := e.subjects.For(event.Type)
+	if subject == "" {
+		return Message{}, fmt.Errorf("no subject configured for event type %s", event.Type)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to marshal event: %v", err)
+	}
+	return Message{EventID: event.ID, Subject: subject, Data: data}, nil
+}
+
+// Fanout публикует событие во все publishers по порядку; ошибка любого из них
+// возвращается relay, и событие будет отправлено всем повторно.
+func Fanout(publishers ...Publisher) Publisher {
+	if len(publishers) == 1 {
+		return publishers[0]
+	}
+	return fanout(publishers)
+}
+
+type fanout []Publisher
+
+func (f fanout) Publish(subject string, data []byte) error {
+	for _, p := range f {
+		if err := p.Publish(subject, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"order-service/internal/models"
+	"testing"
+)
+
+func TestCommitted(t *testing.T) {
+	order := &models.Order{OrderUID: "o1", Status: models.StatusPaid, Version: 3}
+	tests := []struct {
+		name     string
+		found    bool
+		previous models.OrderStatus
+		want     Type
+	}{
+		{"new order", false, models.StatusUnknown, OrderCreated},
+		{"same status", true, models.StatusPaid, OrderUpdated},
+		{"status change", true, models.StatusCreated, OrderStatusChanged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Committed(context.Background(), order, tt.found, tt.previous, []string{"status"})
+			if e.Type != tt.want || e.Revision != 3 || e.OrderUID != "o1" || e.ID == "" {
+				t.Errorf("event = %+v", e)
+			}
+			if (e.PreviousStatus != 0) != (tt.want == OrderStatusChanged) {
+				t.Errorf("previous_status = %v for %s", e.PreviousStatus, e.Type)
+			}
+		})
+	}
+}
+
+// Каждое событие должно соответствовать своей схеме: обязательные поля есть, лишних нет.
+func TestEventsMatchSchemas(t *testing.T) {
+	ctx := context.Background()
+	order := &models.Order{OrderUID: "o1", Status: models.StatusShipped, Version: 2}
+	samples := map[Type]Event{
+		OrderCreated:       Committed(ctx, order, false, 0, nil),
+		OrderUpdated:       Committed(ctx, order, true, models.StatusShipped, []string{"delivery"}),
+		OrderStatusChanged: Committed(ctx, order, true, models.StatusPaid, []string{"status"}),
+		OrderRejected:      Rejected(ctx, "o1", 2, errors.New("track_number is required")),
+	}
+	for _, typ := range Types {
+		t.Run(string(typ), func(t *testing.T) {
+			raw, err := Schema(typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var schema struct {
+				Required   []string                   `json:"required"`
+				Properties map[string]json.RawMessage `json:"properties"`
+			}
+			if err := json.Unmarshal(raw, &schema); err != nil {
+				t.Fatalf("invalid schema: %v", err)
+			}
+
+			data, _ := json.Marshal(samples[typ])
+			var fields map[string]any
+			json.Unmarshal(data, &fields)
+			for _, name := range schema.Required {
+				if _, ok := fields[name]; !ok {
+					t.Errorf("required field %q missing in %s", name, data)
+				}
+			}
+			for name := range fields {
+				if _, ok := schema.Properties[name]; !ok {
+					t.Errorf("field %q is not described in the schema", name)
+				}
+			}
+			if fields["type"] != string(typ) {
+				t.Errorf("type = %v", fields["type"])
+			}
+		})
+	}
+
+	if _, err := Schema("order.unknown"); err == nil {
+		t.Error("expected error for unknown event type")
+	}
+}
+
+func TestEmitter_Encode(t *testing.T) {
+	emitter := NewEmitter(Subjects{Created: "events.created", Rejected: "events.rejected"})
+	ctx := context.Background()
+
+	event := New(ctx, OrderCreated, "o1")
+	msg, err := emitter.Encode(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Event
+	if err := json.Unmarshal(msg.Data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "events.created" || msg.EventID != event.ID || decoded.OrderUID != "o1" {
+		t.Errorf("message = %+v", msg)
+	}
+
+	// Subject не задан
+	if _, err := emitter.Encode(New(ctx, OrderUpdated, "o1")); err == nil {
+		t.Error("expected error for event type without subject")
+	}
+}
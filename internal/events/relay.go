@@ -0,0 +1,128 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"order-service/internal/metrics"
+	"time"
+)
+
+var (
+	eventsPublished = metrics.NewCounter("order_events_published_total", "Domain events published to the broker.")
+	eventsFailed    = metrics.NewCounter("order_events_failed_total", "Attempts to publish a domain event that failed and will be retried.")
+	outboxPurged    = metrics.NewCounter("order_outbox_purged_total", "Sent outbox rows deleted after the retention period.")
+	outboxPending   = metrics.NewGauge("order_outbox_pending", "Outbox events waiting to be published.")
+	outboxLag       = metrics.NewGauge("order_outbox_lag_seconds", "Age of the oldest unpublished outbox event.")
+)
+
+// Record - строка outbox.
+type Record struct {
+	ID int64
+	Message
+	CreatedAt time.Time
+}
+
+// OutboxStore - таблица outbox в БД.
+type OutboxStore interface {
+	// RelayOutbox блокирует до limit неотправленных записей по порядку, передает их в publish
+	// и отмечает отправленными те, что опубликованы до первой ошибки.
+	RelayOutbox(ctx context.Context, limit int, publish func(Record) error) (int, error)
+	// PurgeOutbox удаляет отправленные записи старше retention.
+	PurgeOutbox(ctx context.Context, retention time.Duration) (int64, error)
+	// OutboxBacklog возвращает число неотправленных записей и возраст самой старой.
+	OutboxBacklog(ctx context.Context) (int, time.Duration, error)
+}
+
+type RelayOptions struct {
+	Interval        time.Duration
+	BatchSize       int
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+// Relay публикует события из outbox. Доставка at-least-once: при сбое между
+// публикацией и отметкой событие уйдет повторно, потребители отбрасывают дубли по id.
+type Relay struct {
+	store     OutboxStore
+	publisher Publisher
+	opts      RelayOptions
+}
+
+func NewRelay(store OutboxStore, publisher Publisher, opts RelayOptions) *Relay {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &Relay{store: store, publisher: publisher, opts: opts}
+}
+
+// Run работает до отмены ctx.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	var cleanup <-chan time.Time
+	if r.opts.Retention > 0 && r.opts.CleanupInterval > 0 {
+		t := time.NewTicker(r.opts.CleanupInterval)
+		defer t.Stop()
+		cleanup = t.C
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Flush(ctx)
+		case <-cleanup:
+			r.Purge(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Flush публикует накопившиеся события пачками и обновляет метрики отставания.
+// Ошибка публикации прерывает проход: оставшиеся события уйдут в следующий раз по порядку.
+func (r *Relay) Flush(ctx context.Context) {
+	for {
+		n, err := r.store.RelayOutbox(ctx, r.opts.BatchSize, r.publish)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error relaying outbox events", "error", err, "published", n)
+			break
+		}
+		if n < r.opts.BatchSize {
+			break
+		}
+	}
+
+	pending, lag, err := r.store.OutboxBacklog(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error reading outbox backlog", "error", err)
+		return
+	}
+	outboxPending.Set(float64(pending))
+	outboxLag.Set(lag.Seconds())
+}
+
+func (r *Relay) publish(rec Record) error {
+	if err := r.publisher.Publish(rec.Subject, rec.Data); err != nil {
+		eventsFailed.Inc()
+		return err
+	}
+	eventsPublished.Inc()
+	return nil
+}
+
+// Purge удаляет отправленные события старше срока хранения.
+func (r *Relay) Purge(ctx context.Context) {
+	n, err := r.store.PurgeOutbox(ctx, r.opts.Retention)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error purging outbox", "error", err)
+		return
+	}
+	outboxPurged.Add(uint64(n))
+	if n > 0 {
+		slog.InfoContext(ctx, "Outbox purged", "rows", n)
+	}
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// memOutbox повторяет семантику таблицы outbox в памяти.
+type memOutbox struct {
+	records []Record
+	sent    map[int64]bool
+	purged  time.Duration
+}
+
+func (m *memOutbox) add(subject string) {
+	id := int64(len(m.records) + 1)
+	m.records = append(m.records, Record{ID: id, Message: Message{Subject: subject}, CreatedAt: time.Now()})
+}
+
+func (m *memOutbox) RelayOutbox(ctx context.Context, limit int, publish func(Record) error) (int, error) {
+	n := 0
+	for _, rec := range m.records {
+		if m.sent[rec.ID] {
+			continue
+		}
+		if n == limit {
+			break
+		}
+		if err := publish(rec); err != nil {
+			return n, err
+		}
+		m.sent[rec.ID] = true
+		n++
+	}
+	return n, nil
+}
+
+func (m *memOutbox) PurgeOutbox(ctx context.Context, retention time.Duration) (int64, error) {
+	m.purged = retention
+	return 0, nil
+}
+
+func (m *memOutbox) OutboxBacklog(ctx context.Context) (int, time.Duration, error) {
+	pending := 0
+	var lag time.Duration
+	for _, rec := range m.records {
+		if !m.sent[rec.ID] {
+			pending++
+			lag = max(lag, time.Since(rec.CreatedAt))
+		}
+	}
+	return pending, lag, nil
+}
+
+type flakyPublisher struct {
+	subjects []string
+	failOn   string
+}
+
+func (p *flakyPublisher) Publish(subject string, data []byte) error {
+	if subject == p.failOn {
+		return errors.New("broker unavailable")
+	}
+	p.subjects = append(p.subjects, subject)
+	return nil
+}
+
+func TestRelay_Flush(t *testing.T) {
+	store := &memOutbox{sent: map[int64]bool{}}
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		store.add(s)
+	}
+	pub := &flakyPublisher{failOn: "d"}
+	relay := NewRelay(store, pub, RelayOptions{BatchSize: 2, Retention: time.Hour})
+
+	// Ошибка на d останавливает проход, e не публикуется раньше d
+	relay.Flush(context.Background())
+	if !slices.Equal(pub.subjects, []string{"a", "b", "c"}) {
+		t.Fatalf("published %v", pub.subjects)
+	}
+	if outboxPending.Value() != 2 {
+		t.Errorf("pending gauge = %v, want 2", outboxPending.Value())
+	}
+
+	pub.failOn = ""
+	relay.Flush(context.Background())
+	if !slices.Equal(pub.subjects, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("published %v", pub.subjects)
+	}
+	if outboxPending.Value() != 0 || outboxLag.Value() != 0 {
+		t.Errorf("gauges = %v pending, %vs lag", outboxPending.Value(), outboxLag.Value())
+	}
+
+	relay.Purge(context.Background())
+	if store.purged != time.Hour {
+		t.Errorf("purge retention = %v", store.purged)
+	}
+}
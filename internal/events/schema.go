@@ -0,0 +1,18 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// Schema возвращает JSON Schema события указанного типа.
+func Schema(typ Type) ([]byte, error) {
+	data, err := schemas.ReadFile("schemas/" + string(typ) + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown event type %q", typ)
+	}
+	return data, nil
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Config struct {
+	Level            string // debug, info, warn, error
+	DisableRedaction bool
+}
+
+var level slog.LevelVar
+
+// Setup настраивает JSON-логгер по умолчанию (в том числе для пакета log)
+// с корреляционными ID из контекста и маскированием персональных данных.
+func Setup(cfg Config) (*slog.Logger, error) {
+	return setup(os.Stdout, cfg)
+}
+
+func setup(w io.Writer, cfg Config) (*slog.Logger, error) {
+	if err := SetLevel(cfg.Level); err != nil {
+		return nil, err
+	}
+
+	var handler slog.Handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: &level})
+	if !cfg.DisableRedaction {
+		handler = NewRedactingHandler(handler, NewRedactor(DefaultSensitiveFields))
+	}
+	handler = &contextHandler{Handler: handler}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// SetLevel меняет уровень логирования без пересоздания логгера.
+func SetLevel(name string) error {
+	if name == "" {
+		name = "info"
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("invalid log level %q: %v", name, err)
+	}
+	level.Set(l)
+	return nil
+}
+
+func Level() slog.Level {
+	return level.Level()
+}
+
+type correlationKey struct{}
+
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// contextHandler добавляет к записи correlation_id и trace_id из контекста.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		r.AddAttrs(slog.String("correlation_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"order-service/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestRedactingHandler_MasksOrderPII(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := setup(&buf, Config{Level: "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := &models.Order{
+		OrderUID: "test-123",
+		Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000", Email: "test@gmail.com"},
+		Payment:  models.Payment{Transaction: "txn-secret", Currency: "USD"},
+		Items:    []models.Item{{Name: "Mascaras"}},
+	}
+	logger.Info("order", "order", order)
+	logger.Debug("raw", "payload", json.RawMessage(`{"delivery":{"phone":"+9720000000"},"payment":{"request_id":"req-secret"}}`))
+
+	out := buf.String()
+	for _, secret := range []string{"Test Testov", "+9720000000", "test@gmail.com", "txn-secret", "req-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Log output leaks %q: %s", secret, out)
+		}
+	}
+	for _, kept := range []string{"test-123", "USD", "Mascaras"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("Log output should keep %q: %s", kept, out)
+		}
+	}
+}
+
+func TestRedaction_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := setup(&buf, Config{DisableRedaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("order", slog.Group("delivery", "phone", "+9720000000"))
+
+	if !strings.Contains(buf.String(), "+9720000000") {
+		t.Errorf("Redaction should be disabled: %s", buf.String())
+	}
+}
+
+func TestContextHandler_CorrelationID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := setup(&buf, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.InfoContext(WithCorrelationID(context.Background(), "orders:42"), "processed")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Log output is not JSON: %v", err)
+	}
+	if entry["correlation_id"] != "orders:42" {
+		t.Errorf("Expected correlation_id orders:42, got %v", entry["correlation_id"])
+	}
+}
+
+func TestSetLevel_Invalid(t *testing.T) {
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const Mask = "***"
+
+// DefaultSensitiveFields - персональные данные получателя и идентификаторы платежа.
+// Поле совпадает, если путь атрибута (через точку) равен правилу или оканчивается на него.
+var DefaultSensitiveFields = []string{
+	"delivery.name",
+	"delivery.phone",
+	"delivery.zip",
+	"delivery.city",
+	"delivery.address",
+	"delivery.region",
+	"delivery.email",
+	"payment.transaction",
+	"payment.request_id",
+	"phone",
+	"email",
+	"address",
+	"transaction",
+}
+
+type Redactor struct {
+	fields []string
+}
+
+func NewRedactor(fields []string) *Redactor {
+	return &Redactor{fields: fields}
+}
+
+func (r *Redactor) sensitive(path string) bool {
+	for _, f := range r.fields {
+		if path == f || strings.HasSuffix(path, "."+f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Attr возвращает копию атрибута с замаскированными чувствительными полями.
+// prefix - путь групп, в которые вложен атрибут.
+func (r *Redactor) Attr(prefix string, a slog.Attr) slog.Attr {
+	path := a.Key
+	if prefix != "" {
+		path = prefix + "." + a.Key
+	}
+
+	if r.sensitive(path) {
+		return slog.String(a.Key, Mask)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		redacted := make([]any, len(attrs))
+		for i, ga := range attrs {
+			redacted[i] = r.Attr(path, ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny:
+		if structured(v.Any()) {
+			return slog.Any(a.Key, r.value(path, v.Any()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// value переводит структуру в обобщенный JSON-вид и маскирует вложенные поля.
+func (r *Redactor) value(path string, v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Mask
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return Mask
+	}
+	return r.walk(path, generic)
+}
+
+func (r *Redactor) walk(path string, v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			if r.sensitive(childPath) {
+				t[k] = Mask
+				continue
+			}
+			t[k] = r.walk(childPath, child)
+		}
+	case []any:
+		for i, child := range t {
+			t[i] = r.walk(path, child)
+		}
+	}
+	return v
+}
+
+func structured(v any) bool {
+	switch v.(type) {
+	case nil, error, time.Time, time.Duration:
+		return false
+	case json.RawMessage:
+		return true
+	}
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// RedactingHandler маскирует чувствительные атрибуты перед передачей записи дальше.
+type RedactingHandler struct {
+	next   slog.Handler
+	r      *Redactor
+	groups []string
+}
+
+func NewRedactingHandler(next slog.Handler, r *Redactor) *RedactingHandler {
+	return &RedactingHandler{next: next, r: r}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, rec.Message, rec.PC)
+	prefix := strings.Join(h.groups, ".")
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.r.Attr(prefix, a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := strings.Join(h.groups, ".")
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.r.Attr(prefix, a)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted), r: h.r, groups: h.groups}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	groups := append(append([]string{}, h.groups...), name)
+	return &RedactingHandler{next: h.next.WithGroup(name), r: h.r, groups: groups}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Минимальный реестр метрик в текстовом формате Prometheus - без внешних зависимостей.
+
+type metric interface {
+	write(w io.Writer, name string)
+}
+
+type entry struct {
+	help   string
+	kind   string
+	metric metric
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]entry{}
+)
+
+func register(name, help, kind string, m metric) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	registry[name] = entry{help: help, kind: kind, metric: m}
+}
+
+// Counter - монотонно растущий счетчик.
+type Counter struct {
+	v atomic.Uint64
+}
+
+func NewCounter(name, help string) *Counter {
+	c := &Counter{}
+	register(name, help, "counter", c)
+	return c
+}
+
+func (c *Counter) Inc()          { c.v.Add(1) }
+func (c *Counter) Add(n uint64)  { c.v.Add(n) }
+func (c *Counter) Value() uint64 { return c.v.Load() }
+
+func (c *Counter) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %d\n", name, c.Value())
+}
+
+// Gauge - значение, которое может расти и уменьшаться.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{}
+	register(name, help, "gauge", g)
+	return g
+}
+
+func (g *Gauge) Set(v float64)  { g.bits.Store(math.Float64bits(v)) }
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+func (g *Gauge) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(g.Value(), 'g', -1, 64))
+}
+
+// Write выводит все метрики в текстовом формате Prometheus, отсортированные по имени.
+func Write(w io.Writer) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		e := registry[name]
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, e.help, name, e.kind)
+		e.metric.write(w, name)
+	}
+}
+
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
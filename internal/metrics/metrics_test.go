@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	c := NewCounter("test_events_total", "Test events.")
+	g := NewGauge("test_lag_seconds", "Test lag.")
+	c.Inc()
+	c.Add(2)
+	g.Set(1.5)
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		"# TYPE test_events_total counter\ntest_events_total 3\n",
+		"# HELP test_lag_seconds Test lag.\n# TYPE test_lag_seconds gauge\ntest_lag_seconds 1.5\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in output:\n%s", want, body)
+		}
+	}
+	// Метрики отсортированы по имени
+	if strings.Index(body, "test_events_total") > strings.Index(body, "test_lag_seconds") {
+		t.Error("Expected metrics sorted by name")
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	NewCounter("test_dup_total", "Dup.")
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic on duplicate registration")
+		}
+	}()
+	NewCounter("test_dup_total", "Dup.")
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// ChangedFields возвращает JSON-имена полей верхнего уровня, которыми next отличается
+// от prev (вложенные delivery, payment и items сравниваются целиком). prev = nil -
+// заказ новый, изменены все поля.
+func ChangedFields(prev, next *Order) []string {
+	nextFields, err := topLevel(next)
+	if err != nil {
+		return nil
+	}
+	prevFields := map[string]json.RawMessage{}
+	if prev != nil {
+		if prevFields, err = topLevel(prev); err != nil {
+			return nil
+		}
+	}
+
+	var changed []string
+	for name, value := range nextFields {
+		if !bytes.Equal(prevFields[name], value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range prevFields {
+		if _, ok := nextFields[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func topLevel(order *Order) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
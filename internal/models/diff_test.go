@@ -0,0 +1,40 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedFields(t *testing.T) {
+	base := Order{OrderUID: "a", TrackNumber: "T", Status: StatusCreated, Items: []Item{{Rid: "r", Status: ItemPending}}}
+
+	tests := []struct {
+		name   string
+		modify func(o *Order)
+		want   []string
+	}{
+		{"без изменений", func(o *Order) {}, nil},
+		{"статус", func(o *Order) { o.Status = StatusPaid }, []string{"status"}},
+		{"вложенное поле", func(o *Order) { o.Delivery.City = "Moscow" }, []string{"delivery"}},
+		{"позиции и трек", func(o *Order) {
+			o.TrackNumber = "T2"
+			o.Items = []Item{{Rid: "r", Status: ItemShipped}}
+		}, []string{"items", "track_number"}},
+		{"появились предупреждения", func(o *Order) { o.Warnings = []Warning{{Field: "sm_id"}} }, []string{"warnings"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := base
+			next.Items = append([]Item(nil), base.Items...)
+			tt.modify(&next)
+			if got := ChangedFields(&base, &next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChangedFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := ChangedFields(nil, &base); len(got) == 0 {
+		t.Error("New order should report all fields as changed")
+	}
+}
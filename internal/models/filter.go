@@ -0,0 +1,33 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// OrderFilter - условия поиска заказов; пустые поля не ограничивают.
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	Statuses        []OrderStatus
+	// Полуинтервал date_created: [CreatedFrom, CreatedTo)
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+func (f OrderFilter) Match(order *Order) bool {
+	switch {
+	case f.CustomerID != "" && order.CustomerID != f.CustomerID:
+		return false
+	case f.TrackNumber != "" && order.TrackNumber != f.TrackNumber:
+		return false
+	case f.DeliveryService != "" && order.DeliveryService != f.DeliveryService:
+		return false
+	case !f.CreatedFrom.IsZero() && order.DateCreated.Before(f.CreatedFrom):
+		return false
+	case !f.CreatedTo.IsZero() && !order.DateCreated.Before(f.CreatedTo):
+		return false
+	}
+	return len(f.Statuses) == 0 || slices.Contains(f.Statuses, order.Status)
+}
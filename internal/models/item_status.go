@@ -0,0 +1,177 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ItemStatus - нормализованный статус исполнения позиции заказа.
+type ItemStatus string
+
+const (
+	ItemPending    ItemStatus = "pending"
+	ItemProcessing ItemStatus = "processing"
+	ItemShipped    ItemStatus = "shipped"
+	ItemDelivered  ItemStatus = "delivered"
+	ItemCancelled  ItemStatus = "cancelled"
+	ItemReturned   ItemStatus = "returned"
+)
+
+// Числовые коды старых продюсеров (например, "status": 202 в model.json).
+var legacyItemStatusCodes = map[int]ItemStatus{
+	0:   ItemPending,
+	100: ItemPending,
+	200: ItemProcessing,
+	201: ItemProcessing,
+	202: ItemProcessing,
+	300: ItemShipped,
+	301: ItemDelivered,
+	400: ItemCancelled,
+	401: ItemReturned,
+}
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrItemNotFound  = errors.New("item not found")
+)
+
+func (s ItemStatus) Valid() bool {
+	switch s {
+	case ItemPending, ItemProcessing, ItemShipped, ItemDelivered, ItemCancelled, ItemReturned:
+		return true
+	}
+	return false
+}
+
+// ItemStatusFromCode переводит устаревший числовой код через таблицу соответствия.
+func ItemStatusFromCode(code int) (ItemStatus, error) {
+	if status, ok := legacyItemStatusCodes[code]; ok {
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown item status code %d", code)
+}
+
+// ParseItemStatus принимает имя статуса в любом регистре или числовой код строкой.
+// Пустая строка означает pending.
+func ParseItemStatus(raw string) (ItemStatus, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" {
+		return ItemPending, nil
+	}
+	if code, err := strconv.Atoi(raw); err == nil {
+		return ItemStatusFromCode(code)
+	}
+	if status := ItemStatus(raw); status.Valid() {
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown item status %q", raw)
+}
+
+// UnmarshalJSON принимает как строку, так и числовой код.
+func (s *ItemStatus) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = ItemPending
+		return nil
+	}
+
+	var code int
+	if err := json.Unmarshal(data, &code); err == nil {
+		status, err := ItemStatusFromCode(code)
+		if err != nil {
+			return err
+		}
+		*s = status
+		return nil
+	}
+
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("item status must be a string or a number, got %s", data)
+	}
+	status, err := ParseItemStatus(raw)
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// Fulfillment - состояние исполнения заказа, выведенное из статусов позиций.
+type Fulfillment string
+
+const (
+	FulfillmentPending            Fulfillment = "pending"
+	FulfillmentProcessing         Fulfillment = "processing"
+	FulfillmentPartiallyShipped   Fulfillment = "partially_shipped"
+	FulfillmentShipped            Fulfillment = "shipped"
+	FulfillmentPartiallyDelivered Fulfillment = "partially_delivered"
+	FulfillmentDelivered          Fulfillment = "delivered"
+	FulfillmentCancelled          Fulfillment = "cancelled"
+	FulfillmentReturned           Fulfillment = "returned"
+)
+
+// DeriveFulfillment сводит статусы позиций в состояние заказа. Отмененные и
+// возвращенные позиции не учитываются, пока в заказе есть другие.
+func DeriveFulfillment(items []Item) Fulfillment {
+	var active, processing, shipped, delivered, returned int
+	for _, item := range items {
+		switch item.Status {
+		case ItemCancelled:
+			continue
+		case ItemReturned:
+			returned++
+			continue
+		case ItemProcessing:
+			processing++
+		case ItemShipped:
+			shipped++
+		case ItemDelivered:
+			delivered++
+		}
+		active++
+	}
+
+	switch {
+	case len(items) == 0:
+		return FulfillmentPending
+	case active == 0 && returned > 0:
+		return FulfillmentReturned
+	case active == 0:
+		return FulfillmentCancelled
+	case delivered == active:
+		return FulfillmentDelivered
+	case delivered > 0:
+		return FulfillmentPartiallyDelivered
+	case shipped == active:
+		return FulfillmentShipped
+	case shipped > 0:
+		return FulfillmentPartiallyShipped
+	case processing > 0:
+		return FulfillmentProcessing
+	}
+	return FulfillmentPending
+}
+
+// OrderStatus - статус жизненного цикла, которому соответствует состояние исполнения.
+// StatusUnknown означает, что позиции не определяют статус заказа.
+func (f Fulfillment) OrderStatus() OrderStatus {
+	switch f {
+	case FulfillmentShipped, FulfillmentPartiallyDelivered:
+		return StatusShipped
+	case FulfillmentDelivered:
+		return StatusDelivered
+	}
+	return StatusUnknown
+}
+
+// AdvanceStatus - статус заказа после изменения позиций: этап, которому соответствует
+// состояние исполнения f, если граф разрешает переход из current, иначе current.
+func AdvanceStatus(current OrderStatus, f Fulfillment) OrderStatus {
+	if derived := f.OrderStatus(); derived.Valid() && current.CanTransitionTo(derived) {
+		return derived
+	}
+	return current
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestItemStatus_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ItemStatus
+		wantErr bool
+	}{
+		{input: `"shipped"`, want: ItemShipped},
+		{input: `"Delivered"`, want: ItemDelivered},
+		{input: `202`, want: ItemProcessing},
+		{input: `"202"`, want: ItemProcessing},
+		{input: `null`, want: ItemPending},
+		{input: `""`, want: ItemPending},
+		{input: `999`, wantErr: true},
+		{input: `"lost"`, wantErr: true},
+		{input: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got ItemStatus
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Unmarshal(%s) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeriveFulfillment(t *testing.T) {
+	items := func(statuses ...ItemStatus) []Item {
+		var out []Item
+		for _, s := range statuses {
+			out = append(out, Item{Status: s})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		items []Item
+		want  Fulfillment
+	}{
+		{"No items", nil, FulfillmentPending},
+		{"All pending", items(ItemPending, ItemPending), FulfillmentPending},
+		{"Processing", items(ItemPending, ItemProcessing), FulfillmentProcessing},
+		{"Partially shipped", items(ItemShipped, ItemProcessing), FulfillmentPartiallyShipped},
+		{"Shipped ignoring cancelled", items(ItemShipped, ItemCancelled), FulfillmentShipped},
+		{"Partially delivered", items(ItemDelivered, ItemShipped), FulfillmentPartiallyDelivered},
+		{"Fully delivered", items(ItemDelivered, ItemDelivered), FulfillmentDelivered},
+		{"All cancelled", items(ItemCancelled), FulfillmentCancelled},
+		{"All returned", items(ItemReturned, ItemCancelled), FulfillmentReturned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeriveFulfillment(tt.items); got != tt.want {
+				t.Errorf("DeriveFulfillment() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdvanceStatus(t *testing.T) {
+	tests := []struct {
+		current OrderStatus
+		f       Fulfillment
+		want    OrderStatus
+	}{
+		{StatusAssembling, FulfillmentShipped, StatusShipped},
+		{StatusShipped, FulfillmentDelivered, StatusDelivered},
+		{StatusPaid, FulfillmentShipped, StatusPaid},             // сборка пропущена
+		{StatusCancelled, FulfillmentDelivered, StatusCancelled}, // отмененный заказ не оживает
+		{StatusDelivered, FulfillmentShipped, StatusDelivered},   // назад по графу нельзя
+		{StatusAssembling, FulfillmentProcessing, StatusAssembling},
+	}
+
+	for _, tt := range tests {
+		if got := AdvanceStatus(tt.current, tt.f); got != tt.want {
+			t.Errorf("AdvanceStatus(%s, %s) = %s, want %s", tt.current, tt.f, got, tt.want)
+		}
+	}
+}
@@ -1,64 +1,122 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Order struct {
-	OrderUID          string    `json:"order_uid" db:"order_uid"`
-	TrackNumber       string    `json:"track_number" db:"track_number"`
-	Entry             string    `json:"entry" db:"entry"`
-	Delivery          Delivery  `json:"delivery" db:"-"`
-	Payment           Payment   `json:"payment" db:"-"`
-	Items             []Item    `json:"items" db:"-"`
-	Locale            string    `json:"locale" db:"locale"`
-	InternalSignature string    `json:"internal_signature" db:"internal_signature"`
-	CustomerID        string    `json:"customer_id" db:"customer_id"`
-	DeliveryService   string    `json:"delivery_service" db:"delivery_service"`
-	Shardkey          string    `json:"shardkey" db:"shardkey"`
-	SmID              int       `json:"sm_id" db:"sm_id"`
-	DateCreated       time.Time `json:"date_created" db:"date_created"`
-	OofShard          string    `json:"oof_shard" db:"oof_shard"`
-	Status            int       `json:"status" db:"status"`
+	OrderUID          string      `json:"order_uid" xml:"order_uid" db:"order_uid"`
+	TrackNumber       string      `json:"track_number" xml:"track_number" db:"track_number"`
+	Entry             string      `json:"entry" xml:"entry" db:"entry"`
+	Delivery          Delivery    `json:"delivery" xml:"delivery" db:"-"`
+	Payment           Payment     `json:"payment" xml:"payment" db:"-"`
+	Items             []Item      `json:"items" xml:"items>item" db:"-"`
+	Locale            string      `json:"locale" xml:"locale" db:"locale"`
+	InternalSignature string      `json:"internal_signature" xml:"internal_signature" db:"internal_signature"`
+	CustomerID        string      `json:"customer_id" xml:"customer_id" db:"customer_id"`
+	DeliveryService   string      `json:"delivery_service" xml:"delivery_service" db:"delivery_service"`
+	Shardkey          string      `json:"shardkey" xml:"shardkey" db:"shardkey"`
+	SmID              int         `json:"sm_id" xml:"sm_id" db:"sm_id"`
+	DateCreated       time.Time   `json:"date_created" xml:"date_created" db:"date_created"`
+	OofShard          string      `json:"oof_shard" xml:"oof_shard" db:"oof_shard"`
+	Status            OrderStatus `json:"status" xml:"status" db:"status"`
+	Version           int64       `json:"version" xml:"version" db:"version"`
+	Fulfillment       Fulfillment `json:"fulfillment_status" xml:"fulfillment_status" db:"-"`
+	Warnings          []Warning   `json:"warnings,omitempty" xml:"warnings>warning,omitempty" db:"warnings"`
+
+	// ContentHash - канонический хеш содержимого последнего принятого сообщения
+	ContentHash string `json:"-" xml:"-" db:"content_hash"`
+	// UpdatedAt - время последнего сохранения (Last-Modified в HTTP)
+	UpdatedAt time.Time `json:"-" xml:"-" db:"updated_at"`
+}
+
+// OrderState - то, что подписчику нужно знать о сохраненном заказе перед записью.
+type OrderState struct {
+	Status      OrderStatus
+	Version     int64
+	ContentHash string
+}
+
+// Newer сообщает, можно ли записать order поверх сохраненной ревизии current с хешем
+// содержимого currentHash. Равная версия допускается только для того же содержимого
+// (повторная доставка): два разных сообщения с одной версией упорядочить нельзя.
+func (o *Order) Newer(current int64, currentHash string) bool {
+	return o.Version > current || o.Version == current && o.ContentHash == currentHash
+}
+
+// CheckVersion проверяет сообщение, обновляющее сохраненный заказ. Обновление без
+// версии (0) упорядочить нельзя, поэтому оно отклоняется так же, как устаревшее.
+func (o *Order) CheckVersion(current OrderState) error {
+	if o.Version == 0 || !o.Newer(current.Version, current.ContentHash) {
+		return &ErrStaleVersion{OrderUID: o.OrderUID, Current: current.Version, Incoming: o.Version}
+	}
+	return nil
+}
+
+// ErrStaleVersion возвращается при попытке записать ревизию заказа старше сохраненной
+// или другое содержимое с той же версией.
+type ErrStaleVersion struct {
+	OrderUID string
+	Current  int64
+	Incoming int64
+}
+
+func (e *ErrStaleVersion) Error() string {
+	switch e.Incoming {
+	case 0:
+		return fmt.Sprintf("order %s: update without version (stored version %d)", e.OrderUID, e.Current)
+	case e.Current:
+		return fmt.Sprintf("order %s: version %d is already stored with different content", e.OrderUID, e.Incoming)
+	}
+	return fmt.Sprintf("order %s: version %d is older than stored version %d", e.OrderUID, e.Incoming, e.Current)
+}
+
+// Warning - приведение типа, выполненное при мягком разборе сообщения.
+type Warning struct {
+	Field   string `json:"field" xml:"field"`
+	Message string `json:"message" xml:"message"`
 }
 
 type Delivery struct {
-	ID       int    `json:"-"`
-	OrderUID string `json:"-"`
-	Name     string `json:"name"`
-	Phone    string `json:"phone"`
-	Zip      string `json:"zip"`
-	City     string `json:"city"`
-	Address  string `json:"address"`
-	Region   string `json:"region"`
-	Email    string `json:"email"`
+	ID       int    `json:"-" xml:"-"`
+	OrderUID string `json:"-" xml:"-"`
+	Name     string `json:"name" xml:"name"`
+	Phone    string `json:"phone" xml:"phone"`
+	Zip      string `json:"zip" xml:"zip"`
+	City     string `json:"city" xml:"city"`
+	Address  string `json:"address" xml:"address"`
+	Region   string `json:"region" xml:"region"`
+	Email    string `json:"email" xml:"email"`
 }
 
 type Payment struct {
-	Transaction  string `json:"transaction"`
-	OrderUID     string `json:"-"`
-	RequestID    string `json:"request_id"`
-	Currency     string `json:"currency"`
-	Provider     string `json:"provider"`
-	Amount       int    `json:"amount"`
-	PaymentDt    int64  `json:"payment_dt"`
-	Bank         string `json:"bank"`
-	DeliveryCost int    `json:"delivery_cost"`
-	GoodsTotal   int    `json:"goods_total"`
-	CustomFee    int    `json:"custom_fee"`
+	Transaction  string `json:"transaction" xml:"transaction"`
+	OrderUID     string `json:"-" xml:"-"`
+	RequestID    string `json:"request_id" xml:"request_id"`
+	Currency     string `json:"currency" xml:"currency"`
+	Provider     string `json:"provider" xml:"provider"`
+	Amount       int    `json:"amount" xml:"amount"`
+	PaymentDt    int64  `json:"payment_dt" xml:"payment_dt"`
+	Bank         string `json:"bank" xml:"bank"`
+	DeliveryCost int    `json:"delivery_cost" xml:"delivery_cost"`
+	GoodsTotal   int    `json:"goods_total" xml:"goods_total"`
+	CustomFee    int    `json:"custom_fee" xml:"custom_fee"`
 }
 
 type Item struct {
-	ID          int    `json:"-"`
-	OrderUID    string `json:"-"`
-	ChrtID      int    `json:"chrt_id"`
-	TrackNumber string `json:"track_number"`
-	Price       int    `json:"price"`
-	Rid         string `json:"rid"`
-	Name        string `json:"name"`
-	Sale        int    `json:"sale"`
-	Size        string `json:"size"`
-	TotalPrice  int    `json:"total_price"`
-	NmID        int    `json:"nm_id"`
-	Brand       string `json:"brand"`
-	Status      string `json:"status"`
-	Quantity    int    `json:"quantity"`
+	ID          int        `json:"-" xml:"-"`
+	OrderUID    string     `json:"-" xml:"-"`
+	ChrtID      int        `json:"chrt_id" xml:"chrt_id"`
+	TrackNumber string     `json:"track_number" xml:"track_number"`
+	Price       int        `json:"price" xml:"price"`
+	Rid         string     `json:"rid" xml:"rid"`
+	Name        string     `json:"name" xml:"name"`
+	Sale        int        `json:"sale" xml:"sale"`
+	Size        string     `json:"size" xml:"size"`
+	TotalPrice  int        `json:"total_price" xml:"total_price"`
+	NmID        int        `json:"nm_id" xml:"nm_id"`
+	Brand       string     `json:"brand" xml:"brand"`
+	Status      ItemStatus `json:"status" xml:"status"`
+	Quantity    int        `json:"quantity" xml:"quantity"`
 }
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OrderStatus - этап жизненного цикла заказа. В JSON и БД хранится числом.
+type OrderStatus int
+
+const (
+	StatusUnknown    OrderStatus = 0
+	StatusCreated    OrderStatus = 1
+	StatusPaid       OrderStatus = 2
+	StatusAssembling OrderStatus = 3
+	StatusShipped    OrderStatus = 4
+	StatusDelivered  OrderStatus = 5
+	StatusCancelled  OrderStatus = 6
+	StatusReturned   OrderStatus = 7
+)
+
+var statusNames = map[OrderStatus]string{
+	StatusUnknown:    "unknown",
+	StatusCreated:    "created",
+	StatusPaid:       "paid",
+	StatusAssembling: "assembling",
+	StatusShipped:    "shipped",
+	StatusDelivered:  "delivered",
+	StatusCancelled:  "cancelled",
+	StatusReturned:   "returned",
+}
+
+// Граф допустимых переходов: created → paid → assembling → shipped → delivered,
+// отмена возможна до отгрузки, возврат - после доставки.
+var transitions = map[OrderStatus][]OrderStatus{
+	StatusCreated:    {StatusPaid, StatusCancelled},
+	StatusPaid:       {StatusAssembling, StatusCancelled},
+	StatusAssembling: {StatusShipped, StatusCancelled},
+	StatusShipped:    {StatusDelivered},
+	StatusDelivered:  {StatusReturned},
+}
+
+func (s OrderStatus) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("status(%d)", int(s))
+}
+
+// ParseOrderStatus принимает имя статуса в любом регистре или числовой код.
+func ParseOrderStatus(raw string) (OrderStatus, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if code, err := strconv.Atoi(raw); err == nil {
+		if status := OrderStatus(code); status.Valid() {
+			return status, nil
+		}
+		return StatusUnknown, fmt.Errorf("unknown order status %d", code)
+	}
+	for status, name := range statusNames {
+		if name == raw && status.Valid() {
+			return status, nil
+		}
+	}
+	return StatusUnknown, fmt.Errorf("unknown order status %q", raw)
+}
+
+func (s OrderStatus) Valid() bool {
+	return s >= StatusCreated && s <= StatusReturned
+}
+
+// CanTransitionTo сообщает, разрешен ли переход из s в next.
+// Повтор того же статуса переходом не считается и всегда допустим.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrIllegalTransition возвращается при попытке перевести заказ в недопустимый статус.
+type ErrIllegalTransition struct {
+	OrderUID string
+	From     OrderStatus
+	To       OrderStatus
+}
+
+func (e *ErrIllegalTransition) Error() string {
+	return fmt.Sprintf("order %s: illegal status transition %s -> %s", e.OrderUID, e.From, e.To)
+}
+
+// StatusChange - запись истории статусов заказа. From равен StatusUnknown для первой записи.
+type StatusChange struct {
+	From      OrderStatus `json:"from_status"`
+	To        OrderStatus `json:"to_status"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
@@ -0,0 +1,96 @@
+// Package orderpb содержит сгенерированные из proto/order/v1/order.proto типы
+// и их заполнение из моделей.
+package orderpb
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=order-service --go-grpc_out=../.. --go-grpc_opt=module=order-service order/v1/order.proto order/v1/order_service.proto
+
+import (
+	"order-service/internal/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromModel переводит заказ в protobuf-представление.
+func FromModel(order *models.Order) *Order {
+	pb := &Order{
+		OrderUid:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Delivery:          fromDelivery(order.Delivery),
+		Payment:           fromPayment(order.Payment),
+		Items:             make([]*Item, 0, len(order.Items)),
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerId:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		Shardkey:          order.Shardkey,
+		SmId:              int64(order.SmID),
+		OofShard:          order.OofShard,
+		Status:            OrderStatus(order.Status),
+		Version:           order.Version,
+		FulfillmentStatus: string(order.Fulfillment),
+	}
+	if !order.DateCreated.IsZero() {
+		pb.DateCreated = timestamppb.New(order.DateCreated)
+	}
+	for _, item := range order.Items {
+		pb.Items = append(pb.Items, fromItem(item))
+	}
+	for _, w := range order.Warnings {
+		pb.Warnings = append(pb.Warnings, &Warning{Field: w.Field, Message: w.Message})
+	}
+	return pb
+}
+
+// FromModels сохраняет порядок orders.
+func FromModels(orders []*models.Order) *OrderList {
+	list := &OrderList{Orders: make([]*Order, 0, len(orders))}
+	for _, order := range orders {
+		list.Orders = append(list.Orders, FromModel(order))
+	}
+	return list
+}
+
+func fromDelivery(d models.Delivery) *Delivery {
+	return &Delivery{
+		Name:    d.Name,
+		Phone:   d.Phone,
+		Zip:     d.Zip,
+		City:    d.City,
+		Address: d.Address,
+		Region:  d.Region,
+		Email:   d.Email,
+	}
+}
+
+func fromPayment(p models.Payment) *Payment {
+	return &Payment{
+		Transaction:  p.Transaction,
+		RequestId:    p.RequestID,
+		Currency:     p.Currency,
+		Provider:     p.Provider,
+		Amount:       int64(p.Amount),
+		PaymentDt:    p.PaymentDt,
+		Bank:         p.Bank,
+		DeliveryCost: int64(p.DeliveryCost),
+		GoodsTotal:   int64(p.GoodsTotal),
+		CustomFee:    int64(p.CustomFee),
+	}
+}
+
+func fromItem(item models.Item) *Item {
+	return &Item{
+		ChrtId:      int64(item.ChrtID),
+		TrackNumber: item.TrackNumber,
+		Price:       int64(item.Price),
+		Rid:         item.Rid,
+		Name:        item.Name,
+		Sale:        int64(item.Sale),
+		Size:        item.Size,
+		TotalPrice:  int64(item.TotalPrice),
+		NmId:        int64(item.NmID),
+		Brand:       item.Brand,
+		Status:      string(item.Status),
+		Quantity:    int64(item.Quantity),
+	}
+}
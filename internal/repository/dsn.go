@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// connString собирает строку подключения key=value с экранированием значений,
+// поэтому пароли с пробелами и кавычками не ломают ее.
+func connString(cfg DBConfig) (string, error) {
+	var parts []string
+	add := func(key, value string) {
+		parts = append(parts, key+"="+quote(value))
+	}
+
+	if cfg.DSN != "" {
+		base := cfg.DSN
+		if strings.HasPrefix(base, "postgres://") || strings.HasPrefix(base, "postgresql://") {
+			converted, err := pq.ParseURL(base)
+			if err != nil {
+				return "", fmt.Errorf("invalid database DSN: %v", err)
+			}
+			base = converted
+		}
+		parts = append(parts, base)
+	} else {
+		add("host", cfg.Host)
+		add("port", strconv.Itoa(cfg.Port))
+		add("user", cfg.User)
+		add("dbname", cfg.DBName)
+		add("sslmode", cfg.SSLMode)
+		if cfg.Password != "" && cfg.PasswordFile == "" {
+			add("password", cfg.Password)
+		}
+	}
+
+	// Более поздние ключи перекрывают значения из DSN
+	if cfg.PasswordFile != "" {
+		password, err := readSecret(cfg.PasswordFile)
+		if err != nil {
+			return "", err
+		}
+		add("password", password)
+	}
+	if cfg.SSLRootCert != "" {
+		add("sslrootcert", cfg.SSLRootCert)
+	}
+	if cfg.SSLCert != "" {
+		add("sslcert", cfg.SSLCert)
+	}
+	if cfg.SSLKey != "" {
+		add("sslkey", cfg.SSLKey)
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// readSecret читает секрет из файла в стиле Docker/Kubernetes secrets.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %v", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
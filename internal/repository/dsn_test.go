@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConnString(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(secret, []byte("from file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  DBConfig
+		want string
+	}{
+		{
+			name: "Fields with special characters",
+			cfg:  DBConfig{Host: "postgres", Port: 5432, User: "user", Password: `p@ss w'rd\`, DBName: "orders", SSLMode: "disable"},
+			want: `host='postgres' port='5432' user='user' dbname='orders' sslmode='disable' password='p@ss w\'rd\\'`,
+		},
+		{
+			name: "Password file overrides password",
+			cfg:  DBConfig{Host: "postgres", Port: 5432, User: "user", Password: "plain", PasswordFile: secret, DBName: "orders", SSLMode: "disable"},
+			want: `host='postgres' port='5432' user='user' dbname='orders' sslmode='disable' password='from file'`,
+		},
+		{
+			name: "URL DSN with TLS files",
+			cfg:  DBConfig{DSN: "postgres://user:secret@db:5433/orders?sslmode=verify-full", SSLRootCert: "/certs/ca.pem", SSLCert: "/certs/client.pem", SSLKey: "/certs/client.key"},
+			want: `dbname='orders' host='db' password='secret' port='5433' sslmode='verify-full' user='user' sslrootcert='/certs/ca.pem' sslcert='/certs/client.pem' sslkey='/certs/client.key'`,
+		},
+		{
+			name: "Key/value DSN ignores separate fields",
+			cfg:  DBConfig{DSN: "host=db dbname=orders", Host: "ignored", Password: "ignored"},
+			want: `host=db dbname=orders`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := connString(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("connString() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnString_MissingPasswordFile(t *testing.T) {
+	_, err := connString(DBConfig{Host: "postgres", PasswordFile: filepath.Join(t.TempDir(), "missing")})
+	if err == nil {
+		t.Error("Expected error for missing password file")
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"order-service/internal/events"
+	"order-service/internal/tracing"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Ключ advisory-блокировки relay: события публикует один экземпляр сервиса за раз
+const outboxRelayLock = 0x6f7574626f78 // "outbox"
+
+func insertOutbox(ctx context.Context, tx *sql.Tx, messages []events.Message) error {
+	for _, msg := range messages {
+		_, err := execTraced(ctx, tx, "INSERT outbox",
+			"INSERT INTO outbox (event_id, subject, payload) VALUES ($1, $2, $3)",
+			msg.EventID, msg.Subject, string(msg.Data))
+		if err != nil {
+			return fmt.Errorf("failed to save outbox event: %v", err)
+		}
+	}
+	return nil
+}
+
+// EnqueueEvents записывает события, не связанные с изменением заказа (например, об отклоненном сообщении).
+func (r *OrderRepository) EnqueueEvents(ctx context.Context, messages ...events.Message) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertOutbox(ctx, tx, messages); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RelayOutbox публикует пачку неотправленных событий по порядку и отмечает отправленными
+// опубликованные до первой ошибки. Транзакция берет advisory-блокировку: пока один
+// экземпляр сервиса публикует пачку, остальные пропускают свой проход, иначе следующие
+// пачки публиковались бы параллельно и события одного заказа могли бы поменяться местами.
+func (r *OrderRepository) RelayOutbox(ctx context.Context, limit int, publish func(events.Record) error) (sent int, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "repository.RelayOutbox",
+		trace.WithAttributes(attribute.Int("outbox.limit", limit)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", outboxRelayLock).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %v", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT id, event_id, subject, payload, created_at
+        FROM outbox WHERE sent_at IS NULL
+        ORDER BY id LIMIT $1
+    `, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load outbox: %v", err)
+	}
+	var records []events.Record
+	for rows.Next() {
+		var rec events.Record
+		var payload string
+		if err := rows.Scan(&rec.ID, &rec.EventID, &rec.Subject, &payload, &rec.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		rec.Data = []byte(payload)
+		records = append(records, rec)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var ids []int64
+	var publishErr error
+	for _, rec := range records {
+		if publishErr = publish(rec); publishErr != nil {
+			break
+		}
+		ids = append(ids, rec.ID)
+	}
+
+	if len(ids) > 0 {
+		_, err = execTraced(ctx, tx, "UPDATE outbox", "UPDATE outbox SET sent_at = NOW() WHERE id = ANY($1)", pq.Array(ids))
+		if err != nil {
+			return 0, fmt.Errorf("failed to mark outbox events sent: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+	}
+	if publishErr != nil {
+		return len(ids), fmt.Errorf("failed to publish event: %v", publishErr)
+	}
+	return len(ids), nil
+}
+
+func (r *OrderRepository) PurgeOutbox(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM outbox WHERE sent_at < NOW() - $1 * INTERVAL '1 second'", retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// OutboxBacklog считает отставание по времени БД, чтобы не зависеть от часов сервиса.
+func (r *OrderRepository) OutboxBacklog(ctx context.Context) (int, time.Duration, error) {
+	var pending int
+	var lag float64
+	err := r.db.QueryRowContext(ctx, `
+        SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0)
+        FROM outbox WHERE sent_at IS NULL
+    `).Scan(&pending, &lag)
+	if err != nil {
+		return 0, 0, err
+	}
+	return pending, time.Duration(lag * float64(time.Second)), nil
+}
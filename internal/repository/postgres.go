@@ -1,43 +1,84 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"order-service/internal/events"
 	"order-service/internal/models"
+	"order-service/internal/tracing"
+	"time"
 
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type OrderRepository struct {
 	db *sql.DB
 }
 
+// DBConfig описывает подключение либо полем DSN (URL postgres:// или строка key=value),
+// либо отдельными полями. При заданном DSN поля Host, Port, User, Password, DBName
+// и SSLMode игнорируются; PasswordFile и пути к TLS-файлам применяются в обоих случаях.
 type DBConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	DSN          string
+	Host         string
+	Port         int
+	User         string
+	Password     string
+	PasswordFile string
+	DBName       string
+	SSLMode      string
+
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
 func NewPostgresDB(cfg DBConfig) (*sql.DB, error) {
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+	connStr, err := connString(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Printf("Connecting to database: %s@%s:%d/%s", cfg.User, cfg.Host, cfg.Port, cfg.DBName)
+	if cfg.DSN != "" {
+		slog.Info("Connecting to database using DSN")
+	} else {
+		slog.Info("Connecting to database", "user", cfg.User, "host", cfg.Host, "port", cfg.Port, "dbname", cfg.DBName)
+	}
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
 
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	log.Println("Database connected successfully")
+	slog.Info("Database connected successfully")
 	return db, nil
 }
 
@@ -45,43 +86,83 @@ func NewOrderRepository(db *sql.DB) *OrderRepository {
 	return &OrderRepository{db: db}
 }
 
-func (r *OrderRepository) SaveOrder(order *models.Order) error {
-	tx, err := r.db.Begin()
+// SaveOrder сохраняет заказ и записывает события в outbox в той же транзакции.
+func (r *OrderRepository) SaveOrder(ctx context.Context, order *models.Order, outbox ...events.Message) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "repository.SaveOrder",
+		trace.WithAttributes(attribute.String("order.uid", order.OrderUID)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM items WHERE order_uid = $1", order.OrderUID)
+	// Строка заказа обновляется на месте (а не удаляется), чтобы не терять историю статусов
+	prev, exists, err := lockOrder(ctx, tx, order.OrderUID)
+	if err != nil {
+		return fmt.Errorf("failed to lock order: %v", err)
+	}
+	if exists {
+		if err := order.CheckVersion(prev); err != nil {
+			return err
+		}
+	}
+	// Переход проверяется по заблокированной строке: статус мог измениться после проверки
+	// подписчиком (PATCH позиции или сообщение, обработанное другим экземпляром)
+	if exists && !prev.Status.CanTransitionTo(order.Status) {
+		return &models.ErrIllegalTransition{OrderUID: order.OrderUID, From: prev.Status, To: order.Status}
+	}
+	prevStatus := prev.Status
+
+	warnings, err := marshalWarnings(order.Warnings)
+	if err != nil {
+		return err
+	}
+
+	_, err = execTraced(ctx, tx, "DELETE items", "DELETE FROM items WHERE order_uid = $1", order.OrderUID)
 	if err != nil {
 		return fmt.Errorf("failed to delete old items: %v", err)
 	}
 
-	_, err = tx.Exec("DELETE FROM deliveries WHERE order_uid = $1", order.OrderUID)
+	_, err = execTraced(ctx, tx, "DELETE deliveries", "DELETE FROM deliveries WHERE order_uid = $1", order.OrderUID)
 	if err != nil {
 		return fmt.Errorf("failed to delete old delivery: %v", err)
 	}
 
-	_, err = tx.Exec("DELETE FROM payments WHERE order_uid = $1", order.OrderUID)
+	_, err = execTraced(ctx, tx, "DELETE payments", "DELETE FROM payments WHERE order_uid = $1", order.OrderUID)
 	if err != nil {
 		return fmt.Errorf("failed to delete old payment: %v", err)
 	}
 
-	_, err = tx.Exec("DELETE FROM orders WHERE order_uid = $1", order.OrderUID)
+	_, err = execTraced(ctx, tx, "UPSERT orders", `
+        INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status, version, warnings, content_hash, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+        ON CONFLICT (order_uid) DO UPDATE SET
+            track_number = EXCLUDED.track_number, entry = EXCLUDED.entry, locale = EXCLUDED.locale,
+            internal_signature = EXCLUDED.internal_signature, customer_id = EXCLUDED.customer_id,
+            delivery_service = EXCLUDED.delivery_service, shardkey = EXCLUDED.shardkey, sm_id = EXCLUDED.sm_id,
+            date_created = EXCLUDED.date_created, oof_shard = EXCLUDED.oof_shard, status = EXCLUDED.status,
+            version = GREATEST(orders.version, EXCLUDED.version),
+            warnings = EXCLUDED.warnings, content_hash = EXCLUDED.content_hash, updated_at = EXCLUDED.updated_at
+    `, order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard, order.Status, order.Version, warnings, order.ContentHash, order.UpdatedAt)
+
 	if err != nil {
-		return fmt.Errorf("failed to delete old order: %v", err)
+		return fmt.Errorf("failed to save order: %v", err)
 	}
 
-	_, err = tx.Exec(`
-        INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-    `, order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard, order.Status)
+	if !exists || prevStatus != order.Status {
+		_, err = execTraced(ctx, tx, "INSERT order_status_history", `
+            INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at)
+            VALUES ($1, $2, $3, NOW())
+        `, order.OrderUID, prevStatus, order.Status)
 
-	if err != nil {
-		return fmt.Errorf("failed to save order: %v", err)
+		if err != nil {
+			return fmt.Errorf("failed to save status history: %v", err)
+		}
 	}
 
-	_, err = tx.Exec(`
+	_, err = execTraced(ctx, tx, "INSERT deliveries", `
         INSERT INTO deliveries (order_uid, name, phone, zip, city, address, region, email)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
     `, order.OrderUID, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email)
@@ -90,7 +171,7 @@ func (r *OrderRepository) SaveOrder(order *models.Order) error {
 		return fmt.Errorf("failed to save delivery: %v", err)
 	}
 
-	_, err = tx.Exec(`
+	_, err = execTraced(ctx, tx, "INSERT payments", `
         INSERT INTO payments (transaction, order_uid, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
     `, order.Payment.Transaction, order.OrderUID, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee)
@@ -100,7 +181,7 @@ func (r *OrderRepository) SaveOrder(order *models.Order) error {
 	}
 
 	for _, item := range order.Items {
-		_, err = tx.Exec(`
+		_, err = execTraced(ctx, tx, "INSERT items", `
             INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, quantity)
             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
         `, order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status, item.Quantity)
@@ -110,24 +191,75 @@ func (r *OrderRepository) SaveOrder(order *models.Order) error {
 		}
 	}
 
+	if err := insertOutbox(ctx, tx, outbox); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
+func marshalWarnings(warnings []models.Warning) (string, error) {
+	if len(warnings) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(warnings)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode warnings: %v", err)
+	}
+	return string(data), nil
+}
+
+// lockOrder блокирует строку заказа до конца транзакции и возвращает его текущее состояние.
+func lockOrder(ctx context.Context, tx *sql.Tx, uid string) (models.OrderState, bool, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "SELECT orders FOR UPDATE",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")))
+
+	var state models.OrderState
+	err := tx.QueryRowContext(ctx, "SELECT status, version, content_hash FROM orders WHERE order_uid = $1 FOR UPDATE", uid).
+		Scan(&state.Status, &state.Version, &state.ContentHash)
+	if err == sql.ErrNoRows {
+		span.End()
+		return models.OrderState{}, false, nil
+	}
+	tracing.End(span, err)
+	return state, err == nil, err
+}
+
+func execTraced(ctx context.Context, tx *sql.Tx, name, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", query),
+		))
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
 func (r *OrderRepository) GetOrder(uid string) (*models.Order, error) {
 	var order models.Order
+	var warnings []byte
 
 	err := r.db.QueryRow(`
-        SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status
+        SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status, version, warnings, updated_at
         FROM orders WHERE order_uid = $1
     `, uid).Scan(
 		&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
 		&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard, &order.Status,
+		&order.Version, &warnings, &order.UpdatedAt,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if err := json.Unmarshal(warnings, &order.Warnings); err != nil {
+		return nil, fmt.Errorf("failed to decode warnings: %v", err)
+	}
+
 	err = r.db.QueryRow(`
         SELECT name, phone, zip, city, address, region, email
         FROM deliveries WHERE order_uid = $1
@@ -175,9 +307,139 @@ func (r *OrderRepository) GetOrder(uid string) (*models.Order, error) {
 		order.Items = append(order.Items, item)
 	}
 
+	order.Fulfillment = models.DeriveFulfillment(order.Items)
 	return &order, nil
 }
 
+// UpdateItemStatus меняет статус одной позиции и продвигает статус заказа, если
+// позиции переводят его на следующий этап (см. models.AdvanceStatus). Статус заказа
+// выводится из позиций и проверяется по графу в той же транзакции, под блокировкой строки
+// заказа, поэтому параллельная отмена или более новый статус не перезаписываются.
+// События, которые строит outbox по итоговому состоянию, записываются в той же транзакции.
+func (r *OrderRepository) UpdateItemStatus(ctx context.Context, uid, rid string, status models.ItemStatus, outbox events.OutboxFunc) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "repository.UpdateItemStatus",
+		trace.WithAttributes(attribute.String("order.uid", uid), attribute.String("item.rid", rid)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	prev, exists, err := lockOrder(ctx, tx, uid)
+	if err != nil {
+		return fmt.Errorf("failed to lock order: %v", err)
+	}
+	if !exists {
+		return models.ErrOrderNotFound
+	}
+
+	result, err := execTraced(ctx, tx, "UPDATE items",
+		"UPDATE items SET status = $1 WHERE order_uid = $2 AND rid = $3", status, uid, rid)
+	if err != nil {
+		return fmt.Errorf("failed to update item status: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return models.ErrItemNotFound
+	}
+
+	items, err := itemStatuses(ctx, tx, uid)
+	if err != nil {
+		return fmt.Errorf("failed to load item statuses: %v", err)
+	}
+	orderStatus := models.AdvanceStatus(prev.Status, models.DeriveFulfillment(items))
+
+	// PATCH - новая ревизия заказа: с увеличенной версией повторная доставка или replay
+	// последнего сообщения брокера отклоняется как устаревшая и не откатывает статусы.
+	// Хеш сбрасывается - заказ больше не совпадает с содержимым этого сообщения
+	current := models.OrderState{Status: orderStatus}
+	updateCtx, span := tracing.Tracer().Start(ctx, "UPDATE orders",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")))
+	err = tx.QueryRowContext(updateCtx, `
+        UPDATE orders SET status = $1, version = version + 1, content_hash = '', updated_at = NOW()
+        WHERE order_uid = $2 RETURNING version
+    `, orderStatus, uid).Scan(&current.Version)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to update order: %v", err)
+	}
+
+	if orderStatus != prev.Status {
+		_, err = execTraced(ctx, tx, "INSERT order_status_history", `
+            INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at)
+            VALUES ($1, $2, $3, NOW())
+        `, uid, prev.Status, orderStatus)
+		if err != nil {
+			return fmt.Errorf("failed to save status history: %v", err)
+		}
+	}
+
+	if outbox != nil {
+		if err := insertOutbox(ctx, tx, outbox(uid, current, prev.Status)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// itemStatuses читает статусы позиций заказа в транзакции, которая держит блокировку заказа.
+func itemStatuses(ctx context.Context, tx *sql.Tx, uid string) ([]models.Item, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT status FROM items WHERE order_uid = $1", uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.Item
+	for rows.Next() {
+		var item models.Item
+		if err := rows.Scan(&item.Status); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetOrderState возвращает текущий статус, версию и хеш содержимого заказа; found = false, если заказа нет.
+func (r *OrderRepository) GetOrderState(ctx context.Context, uid string) (state models.OrderState, found bool, err error) {
+	err = r.db.QueryRowContext(ctx, "SELECT status, version, content_hash FROM orders WHERE order_uid = $1", uid).
+		Scan(&state.Status, &state.Version, &state.ContentHash)
+	if err == sql.ErrNoRows {
+		return models.OrderState{}, false, nil
+	}
+	if err != nil {
+		return models.OrderState{}, false, err
+	}
+	return state, true, nil
+}
+
+func (r *OrderRepository) GetStatusHistory(ctx context.Context, uid string) ([]models.StatusChange, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT from_status, to_status, changed_at
+        FROM order_status_history WHERE order_uid = $1
+        ORDER BY changed_at, id
+    `, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.StatusChange{}
+	for rows.Next() {
+		var change models.StatusChange
+		if err := rows.Scan(&change.From, &change.To, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+
+	return history, rows.Err()
+}
+
 func (r *OrderRepository) GetAllOrders() ([]*models.Order, error) {
 	rows, err := r.db.Query("SELECT order_uid FROM orders")
 	if err != nil {
@@ -194,7 +456,7 @@ func (r *OrderRepository) GetAllOrders() ([]*models.Order, error) {
 
 		order, err := r.GetOrder(uid)
 		if err != nil {
-			log.Printf("Error loading order %s: %v", uid, err)
+			slog.Error("Error loading order", "order_uid", uid, "error", err)
 			continue
 		}
 
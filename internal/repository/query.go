@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"order-service/internal/models"
+	"order-service/internal/tracing"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetOrders загружает заказы пачкой - по запросу на таблицу, а не на каждый заказ.
+// Отсутствующие uid пропускаются; порядок результата не определен.
+func (r *OrderRepository) GetOrders(ctx context.Context, uids []string) (orders []*models.Order, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "repository.GetOrders",
+		trace.WithAttributes(attribute.Int("orders.requested", len(uids))))
+	defer func() { tracing.End(span, err) }()
+
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	byUID := make(map[string]*models.Order, len(uids))
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status, version, warnings, updated_at
+        FROM orders WHERE order_uid = ANY($1)
+    `, pq.Array(uids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var order models.Order
+		var warnings []byte
+		if err := rows.Scan(
+			&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+			&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard, &order.Status,
+			&order.Version, &warnings, &order.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(warnings, &order.Warnings); err != nil {
+			return nil, fmt.Errorf("failed to decode warnings of order %s: %v", order.OrderUID, err)
+		}
+		byUID[order.OrderUID] = &order
+		orders = append(orders, &order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	found := make([]string, 0, len(byUID))
+	for uid := range byUID {
+		found = append(found, uid)
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+        SELECT order_uid, name, phone, zip, city, address, region, email
+        FROM deliveries WHERE order_uid = ANY($1)
+    `, pq.Array(found))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var uid string
+		var d models.Delivery
+		if err := rows.Scan(&uid, &d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email); err != nil {
+			return nil, err
+		}
+		byUID[uid].Delivery = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+        SELECT order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
+        FROM payments WHERE order_uid = ANY($1)
+    `, pq.Array(found))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var uid string
+		var p models.Payment
+		if err := rows.Scan(
+			&uid, &p.Transaction, &p.RequestID, &p.Currency, &p.Provider,
+			&p.Amount, &p.PaymentDt, &p.Bank, &p.DeliveryCost, &p.GoodsTotal, &p.CustomFee,
+		); err != nil {
+			return nil, err
+		}
+		byUID[uid].Payment = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+        SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, quantity
+        FROM items WHERE order_uid = ANY($1) ORDER BY id
+    `, pq.Array(found))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var uid string
+		var item models.Item
+		if err := rows.Scan(
+			&uid, &item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size,
+			&item.TotalPrice, &item.NmID, &item.Brand, &item.Status, &item.Quantity,
+		); err != nil {
+			return nil, err
+		}
+		byUID[uid].Items = append(byUID[uid].Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		order.Fulfillment = models.DeriveFulfillment(order.Items)
+	}
+	span.SetAttributes(attribute.Int("orders.found", len(orders)))
+	return orders, nil
+}
+
+// orderFilterSQL - условие WHERE для filter с параметрами начиная с $1.
+const orderFilterSQL = `($1 = '' OR customer_id = $1)
+          AND ($2 = '' OR track_number = $2)
+          AND ($3 = '' OR delivery_service = $3)
+          AND (cardinality($4::integer[]) = 0 OR status = ANY($4::integer[]))
+          AND ($5::timestamp IS NULL OR date_created >= $5::timestamp)
+          AND ($6::timestamp IS NULL OR date_created < $6::timestamp)`
+
+func orderFilterArgs(filter models.OrderFilter) []any {
+	statuses := make([]int64, len(filter.Statuses))
+	for i, status := range filter.Statuses {
+		statuses[i] = int64(status)
+	}
+	return []any{filter.CustomerID, filter.TrackNumber, filter.DeliveryService, pq.Array(statuses),
+		nullTime(filter.CreatedFrom), nullTime(filter.CreatedTo)}
+}
+
+// nullTime - NULL вместо нулевого времени: граница интервала не задана.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// FindOrders возвращает до limit order_uid, подходящих под filter, по возрастанию
+// начиная после after (курсор - последний uid предыдущей страницы).
+func (r *OrderRepository) FindOrders(ctx context.Context, filter models.OrderFilter, after string, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT order_uid FROM orders
+        WHERE `+orderFilterSQL+`
+          AND order_uid > $7
+        ORDER BY order_uid LIMIT $8
+    `, append(orderFilterArgs(filter), after, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uids []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		uids = append(uids, uid)
+	}
+	return uids, rows.Err()
+}
+
+func (r *OrderRepository) CountOrders(ctx context.Context, filter models.OrderFilter) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE `+orderFilterSQL, orderFilterArgs(filter)...).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"order-service/internal/events"
+	"order-service/internal/webhook"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, w *webhook.Webhook) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO webhooks (id, url, events, secret, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `, w.ID, w.URL, pq.Array(eventTypes(w.Events)), w.Secret, w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListWebhooks(ctx context.Context) ([]*webhook.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, url, events, secret, created_at FROM webhooks ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*webhook.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *WebhookRepository) GetWebhook(ctx context.Context, id string) (*webhook.Webhook, error) {
+	w, err := scanWebhook(r.db.QueryRowContext(ctx, "SELECT id, url, events, secret, created_at FROM webhooks WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, webhook.ErrNotFound
+	}
+	return w, err
+}
+
+func scanWebhook(row interface{ Scan(...any) error }) (*webhook.Webhook, error) {
+	var w webhook.Webhook
+	var types []string
+	if err := row.Scan(&w.ID, &w.URL, pq.Array(&types), &w.Secret, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		w.Events = append(w.Events, events.Type(t))
+	}
+	return &w, nil
+}
+
+func eventTypes(types []events.Type) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
+
+// CreateDeliveries пропускает доставки, уже созданные для пары вебхук - событие:
+// relay может передать событие повторно, если не успел отметить его отправленным.
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*webhook.Delivery) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range deliveries {
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            ON CONFLICT (webhook_id, event_id) DO NOTHING
+            RETURNING id
+        `, d.WebhookID, d.EventID, string(d.EventType), string(d.Payload), d.Status, d.NextAttemptAt, d.CreatedAt).Scan(&d.ID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save webhook delivery: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimDeliveries откладывает выбранные доставки на lease одним запросом; SKIP LOCKED
+// не дает двум экземплярам сервиса забрать одну доставку.
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*webhook.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        WITH due AS (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at, id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE webhook_deliveries d
+        SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+        FROM due, webhooks w
+        WHERE d.id = due.id AND w.id = d.webhook_id
+        RETURNING d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.created_at, w.url, w.secret
+    `, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*webhook.Delivery
+	for rows.Next() {
+		var d webhook.Delivery
+		var payload string
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.AttemptCount, &d.CreatedAt, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, deliveryID int64, attempt webhook.Attempt, status string, next time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO webhook_attempts (delivery_id, number, attempted_at, status_code, error, duration_ms)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, deliveryID, attempt.Number, attempt.At, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook attempt: %v", err)
+	}
+
+	// Завершенная доставка больше не выбирается, next_attempt_at остается как есть
+	_, err = tx.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = attempts + 1,
+            next_attempt_at = CASE WHEN $2 = 'pending' THEN $3 ELSE next_attempt_at END
+        WHERE id = $1
+    `, deliveryID, status, next)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*webhook.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, webhook_id, event_id, event_type, status, attempts, next_attempt_at, created_at
+        FROM webhook_deliveries WHERE webhook_id = $1
+        ORDER BY id DESC LIMIT $2
+    `, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*webhook.Delivery{}
+	byID := map[int64]*webhook.Delivery{}
+	var ids []int64
+	for rows.Next() {
+		d := &webhook.Delivery{Attempts: []webhook.Attempt{}}
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.AttemptCount, &d.NextAttemptAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+		byID[d.ID] = d
+		ids = append(ids, d.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	attempts, err := r.db.QueryContext(ctx, `
+        SELECT delivery_id, number, attempted_at, status_code, error, duration_ms
+        FROM webhook_attempts WHERE delivery_id = ANY($1)
+        ORDER BY delivery_id, number
+    `, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer attempts.Close()
+	for attempts.Next() {
+		var id int64
+		var a webhook.Attempt
+		if err := attempts.Scan(&id, &a.Number, &a.At, &a.StatusCode, &a.Error, &a.DurationMs); err != nil {
+			return nil, err
+		}
+		byID[id].Attempts = append(byID[id].Attempts, a)
+	}
+	return deliveries, attempts.Err()
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"order-service/internal/cache"
+	"order-service/internal/events"
+	"order-service/internal/models"
+)
+
+type ItemStore interface {
+	GetOrder(uid string) (*models.Order, error)
+	UpdateItemStatus(ctx context.Context, uid, rid string, status models.ItemStatus, outbox events.OutboxFunc) error
+}
+
+// OrderService - операции над отдельными заказами вне потока NATS.
+type OrderService struct {
+	repo  ItemStore
+	cache *cache.Cache
+	// Доменные события в outbox; nil - не записываются
+	events *events.Emitter
+}
+
+func NewOrderService(repo ItemStore, cache *cache.Cache) *OrderService {
+	return &OrderService{repo: repo, cache: cache}
+}
+
+// SetEventEmitter включает события об изменениях через PATCH - те же, что пишет подписчик.
+func (s *OrderService) SetEventEmitter(emitter *events.Emitter) {
+	s.events = emitter
+}
+
+// UpdateItemStatus меняет статус одной позиции без переотправки всего заказа.
+// Если позиции переводят заказ на следующий этап (например, все доставлены),
+// статус заказа продвигается, когда переход разрешен графом. Решение принимает
+// хранилище в транзакции, а заказ для ответа и кэша перечитывается после записи.
+func (s *OrderService) UpdateItemStatus(ctx context.Context, uid, rid string, status models.ItemStatus) (*models.Order, error) {
+	if !status.Valid() {
+		return nil, fmt.Errorf("unknown item status %q", status)
+	}
+
+	var outbox events.OutboxFunc
+	if s.events != nil {
+		outbox = func(orderUID string, current models.OrderState, previous models.OrderStatus) []events.Message {
+			return s.itemEvents(ctx, orderUID, current, previous)
+		}
+	}
+	if err := s.repo.UpdateItemStatus(ctx, uid, rid, status, outbox); err != nil {
+		return nil, err
+	}
+
+	order, err := s.repo.GetOrder(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(order)
+	slog.InfoContext(ctx, "Item status updated", "order_uid", uid, "rid", rid, "status", status,
+		"fulfillment", order.Fulfillment, "order_status", order.Status.String())
+	return order, nil
+}
+
+// itemEvents строит событие о PATCH позиции: status_changed, если статус заказа сдвинулся,
+// иначе updated.
+func (s *OrderService) itemEvents(ctx context.Context, uid string, current models.OrderState, previous models.OrderStatus) []events.Message {
+	changed := []string{"items", "version"}
+	if current.Status != previous {
+		changed = []string{"items", "status", "version"}
+	}
+	order := &models.Order{OrderUID: uid, Status: current.Status, Version: current.Version}
+	msg, err := s.events.Encode(events.Committed(ctx, order, true, previous, changed))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error encoding order event", "error", err, "order_uid", uid)
+		return nil
+	}
+	return []events.Message{msg}
+}
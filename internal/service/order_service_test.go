@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"order-service/internal/cache"
+	"order-service/internal/envelope"
+	"order-service/internal/events"
+	"order-service/internal/models"
+	"testing"
+)
+
+// fakeItemStore повторяет репозиторий: статус заказа выводится из позиций при записи.
+type fakeItemStore struct {
+	order  *models.Order
+	outbox []events.Message
+}
+
+func (s *fakeItemStore) GetOrder(uid string) (*models.Order, error) {
+	if s.order == nil || s.order.OrderUID != uid {
+		return nil, sql.ErrNoRows
+	}
+	order := *s.order
+	order.Items = append([]models.Item(nil), s.order.Items...)
+	order.Fulfillment = models.DeriveFulfillment(order.Items)
+	return &order, nil
+}
+
+func (s *fakeItemStore) UpdateItemStatus(ctx context.Context, uid, rid string, status models.ItemStatus, outbox events.OutboxFunc) error {
+	if s.order == nil || s.order.OrderUID != uid {
+		return models.ErrOrderNotFound
+	}
+	found := false
+	for i := range s.order.Items {
+		if s.order.Items[i].Rid == rid {
+			s.order.Items[i].Status = status
+			found = true
+		}
+	}
+	if !found {
+		return models.ErrItemNotFound
+	}
+	previous := s.order.Status
+	s.order.Status = models.AdvanceStatus(s.order.Status, models.DeriveFulfillment(s.order.Items))
+	// Как и репозиторий: PATCH - новая ревизия, а хеш последнего сообщения больше не совпадает
+	s.order.Version++
+	s.order.ContentHash = ""
+	if outbox != nil {
+		s.outbox = append(s.outbox, outbox(uid, models.OrderState{Status: s.order.Status, Version: s.order.Version}, previous)...)
+	}
+	return nil
+}
+
+// SaveOrder и GetOrderState позволяют подписчику писать в то же хранилище.
+func (s *fakeItemStore) SaveOrder(ctx context.Context, order *models.Order, outbox ...events.Message) error {
+	if s.order != nil {
+		if err := order.CheckVersion(models.OrderState{Version: s.order.Version, ContentHash: s.order.ContentHash}); err != nil {
+			return err
+		}
+	}
+	saved := *order
+	saved.Items = append([]models.Item(nil), order.Items...)
+	s.order = &saved
+	return nil
+}
+
+func (s *fakeItemStore) GetOrderState(ctx context.Context, uid string) (models.OrderState, bool, error) {
+	if s.order == nil || s.order.OrderUID != uid {
+		return models.OrderState{}, false, nil
+	}
+	return models.OrderState{Status: s.order.Status, Version: s.order.Version, ContentHash: s.order.ContentHash}, true, nil
+}
+
+func TestOrderService_UpdateItemStatus(t *testing.T) {
+	store := &fakeItemStore{order: &models.Order{
+		OrderUID: "test-123",
+		Status:   models.StatusShipped,
+		Items: []models.Item{
+			{Rid: "rid-1", Status: models.ItemDelivered},
+			{Rid: "rid-2", Status: models.ItemShipped},
+		},
+	}}
+	c := cache.New()
+	svc := NewOrderService(store, c)
+
+	order, err := svc.UpdateItemStatus(context.Background(), "test-123", "rid-2", models.ItemDelivered)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.Fulfillment != models.FulfillmentDelivered {
+		t.Errorf("Expected fulfillment delivered, got %s", order.Fulfillment)
+	}
+	if order.Status != models.StatusDelivered || store.order.Status != models.StatusDelivered {
+		t.Errorf("Order status should advance to delivered, got %s", order.Status)
+	}
+	if cached, ok := c.Get("test-123"); !ok || cached.Items[1].Status != models.ItemDelivered {
+		t.Error("Cache should hold the updated order")
+	}
+}
+
+func TestOrderService_UpdateItemStatusKeepsIllegalOrderStatus(t *testing.T) {
+	// Из paid нельзя сразу перейти в shipped, поэтому статус заказа не меняется
+	store := &fakeItemStore{order: &models.Order{
+		OrderUID: "test-123",
+		Status:   models.StatusPaid,
+		Items:    []models.Item{{Rid: "rid-1", Status: models.ItemPending}},
+	}}
+	svc := NewOrderService(store, cache.New())
+
+	order, err := svc.UpdateItemStatus(context.Background(), "test-123", "rid-1", models.ItemShipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != models.StatusPaid {
+		t.Errorf("Expected status paid, got %s", order.Status)
+	}
+	if order.Fulfillment != models.FulfillmentShipped {
+		t.Errorf("Expected fulfillment shipped, got %s", order.Fulfillment)
+	}
+}
+
+func TestOrderService_UpdateItemStatusKeepsCancelledOrder(t *testing.T) {
+	// Заказ отменили, пока клиент смотрел на позиции: доставка позиции его не возвращает
+	store := &fakeItemStore{order: &models.Order{
+		OrderUID: "test-123",
+		Status:   models.StatusCancelled,
+		Items:    []models.Item{{Rid: "rid-1", Status: models.ItemShipped}},
+	}}
+	svc := NewOrderService(store, cache.New())
+
+	order, err := svc.UpdateItemStatus(context.Background(), "test-123", "rid-1", models.ItemDelivered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != models.StatusCancelled || store.order.Status != models.StatusCancelled {
+		t.Errorf("Expected order to stay cancelled, got %s", order.Status)
+	}
+}
+
+func TestOrderService_UpdateItemStatusNotFound(t *testing.T) {
+	store := &fakeItemStore{order: &models.Order{OrderUID: "test-123", Items: []models.Item{{Rid: "rid-1"}}}}
+	svc := NewOrderService(store, cache.New())
+
+	if _, err := svc.UpdateItemStatus(context.Background(), "missing", "rid-1", models.ItemShipped); !errors.Is(err, models.ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+	if _, err := svc.UpdateItemStatus(context.Background(), "test-123", "missing", models.ItemShipped); !errors.Is(err, models.ErrItemNotFound) {
+		t.Errorf("Expected ErrItemNotFound, got %v", err)
+	}
+}
+
+func TestOrderService_RedeliveryDoesNotRevertPatch(t *testing.T) {
+	store := &fakeItemStore{}
+	c := cache.New()
+	sub := NewSubscriber(nil, store, c)
+	svc := NewOrderService(store, c)
+	msg := envelope.New("test", []byte(`{"order_uid":"test-123","track_number":"T","payment":{"transaction":"t"},
+		"version":5,"status":4,"items":[{"rid":"rid-1","status":"shipped"},{"rid":"rid-2","status":"shipped"}]}`))
+
+	if err := sub.process(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.UpdateItemStatus(context.Background(), "test-123", "rid-1", models.ItemDelivered); err != nil {
+		t.Fatal(err)
+	}
+
+	// Повторная доставка того же сообщения старше PATCH и не откатывает позицию
+	var stale *models.ErrStaleVersion
+	if err := sub.process(context.Background(), msg); !errors.As(err, &stale) {
+		t.Fatalf("Expected ErrStaleVersion on redelivery, got %v", err)
+	}
+	if status := store.order.Items[0].Status; status != models.ItemDelivered {
+		t.Errorf("Stored item status = %s, want delivered", status)
+	}
+	if cached, _ := c.Get("test-123"); cached == nil || cached.Items[0].Status != models.ItemDelivered {
+		t.Error("Cache should keep the patched item")
+	}
+}
+
+func TestOrderService_UpdateItemStatusEvents(t *testing.T) {
+	store := &fakeItemStore{order: &models.Order{
+		OrderUID: "test-123",
+		Status:   models.StatusShipped,
+		Version:  7,
+		Items: []models.Item{
+			{Rid: "rid-1", Status: models.ItemShipped},
+			{Rid: "rid-2", Status: models.ItemShipped},
+		},
+	}}
+	svc := NewOrderService(store, cache.New())
+	svc.SetEventEmitter(events.NewEmitter(events.Subjects{Updated: "orders.updated", StatusChanged: "orders.status_changed"}))
+
+	tests := []struct {
+		rid      string
+		subject  string
+		typ      events.Type
+		previous models.OrderStatus
+		revision int64
+	}{
+		// Одна позиция доставлена - статус заказа прежний
+		{"rid-1", "orders.updated", events.OrderUpdated, 0, 8},
+		// Доставлены все - заказ переходит в delivered
+		{"rid-2", "orders.status_changed", events.OrderStatusChanged, models.StatusShipped, 9},
+	}
+	for i, tt := range tests {
+		if _, err := svc.UpdateItemStatus(context.Background(), "test-123", tt.rid, models.ItemDelivered); err != nil {
+			t.Fatal(err)
+		}
+		if len(store.outbox) != i+1 {
+			t.Fatalf("Expected %d outbox messages, got %d", i+1, len(store.outbox))
+		}
+		msg := store.outbox[i]
+		var event events.Event
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Subject != tt.subject || event.Type != tt.typ || event.PreviousStatus != tt.previous || event.Revision != tt.revision {
+			t.Errorf("%s: subject %s, event %+v", tt.rid, msg.Subject, event)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"order-service/internal/broker"
+	"order-service/internal/cache"
+	"order-service/internal/envelope"
+	"order-service/internal/events"
+	"order-service/internal/models"
+	"sync"
+	"testing"
+)
+
+// flakyStore отвечает временной ошибкой на первые failures вызовов SaveOrder.
+type flakyStore struct {
+	mu       sync.Mutex
+	failures int
+	saves    int
+	states   map[string]models.OrderState
+	orders   map[string]*models.Order
+}
+
+func (s *flakyStore) SaveOrder(ctx context.Context, order *models.Order, outbox ...events.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("connection refused")
+	}
+	s.saves++
+	if s.orders != nil {
+		s.orders[order.OrderUID] = order
+	}
+	s.states[order.OrderUID] = models.OrderState{Status: order.Status, Version: order.Version, ContentHash: order.ContentHash}
+	return nil
+}
+
+func (s *flakyStore) GetOrder(uid string) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, ok := s.orders[uid]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return order, nil
+}
+
+func (s *flakyStore) GetOrderState(ctx context.Context, uid string) (models.OrderState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, found := s.states[uid]
+	return state, found, nil
+}
+
+// Весь конвейер без внешнего брокера: конверт, разбор, валидация, сохранение, кэш и подтверждение.
+func TestSubscriber_Pipeline(t *testing.T) {
+	src := broker.NewMemory("orders")
+	// Первое сохранение падает - сообщение должно обработаться повторно
+	store := &flakyStore{failures: 1, states: map[string]models.OrderState{}}
+	c := cache.New()
+	sub := NewSubscriber(src, store, c)
+	if err := sub.Start(context.Background(), 4, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(payload string) {
+		t.Helper()
+		data, err := envelope.New("test", []byte(payload)).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := src.Publish(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 1; i <= 5; i++ {
+		publish(fmt.Sprintf(`{"order_uid":"p-%d","track_number":"T%d","payment":{"transaction":"txn"},"version":1}`, i, i))
+	}
+	// Обновления одного заказа по порядку: повтор, новая ревизия, устаревшая ревизия
+	publish(`{"order_uid":"p-1","track_number":"T1","payment":{"transaction":"txn"},"version":1}`)
+	publish(`{"order_uid":"p-1","track_number":"T1-v2","payment":{"transaction":"txn"},"version":2,"status":2}`)
+	publish(`{"order_uid":"p-1","track_number":"T1-old","payment":{"transaction":"txn"},"version":1}`)
+	// Заказ без конверта (схема v1): статус 2 = In Transit -> shipped
+	src.Publish([]byte(`{"order_uid":"legacy","track_number":"L","payment":{"transaction":"txn"},"status":2,"items":[{"rid":"r","status":202}]}`))
+	// Невалидные сообщения подтверждаются и не повторяются
+	publish(`{"track_number":"no-uid"}`)
+	src.Publish([]byte(`not json`))
+
+	waitIdle(t, src)
+	sub.Close()
+
+	if len(c.GetAll()) != 6 {
+		t.Errorf("Expected 6 cached orders, got %d", len(c.GetAll()))
+	}
+	order, ok := c.Get("p-1")
+	if !ok || order.TrackNumber != "T1-v2" || order.Version != 2 || order.Status != models.StatusPaid {
+		t.Errorf("Expected p-1 at version 2 paid, got %+v", order)
+	}
+	legacy, ok := c.Get("legacy")
+	if !ok || legacy.Status != models.StatusShipped || legacy.Items[0].Status != models.ItemProcessing {
+		t.Errorf("Expected upgraded legacy order, got %+v", legacy)
+	}
+	// 5 новых заказов + новая ревизия p-1 + legacy; дубликат и устаревшая ревизия не сохраняются
+	if store.saves != 7 {
+		t.Errorf("Expected 7 saves, got %d", store.saves)
+	}
+}
+
+// Временная ошибка повторяется на месте: следующее сообщение того же заказа не обгоняет
+// неудавшееся, и брокер не получает отказ.
+func TestSubscriber_RetriesInPlace(t *testing.T) {
+	src := broker.NewMemory("orders")
+	store := &flakyStore{failures: 2, states: map[string]models.OrderState{}}
+	c := cache.New()
+	sub := NewSubscriber(src, store, c)
+	if err := sub.Start(context.Background(), 2, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	retried, unacked := retriedInPlace.Value(), redeliveryLeft.Value()
+	for _, payload := range []string{
+		`{"order_uid":"r-1","track_number":"T1","payment":{"transaction":"txn"},"version":1}`,
+		`{"order_uid":"r-1","track_number":"T2","payment":{"transaction":"txn"},"version":2}`,
+	} {
+		data, _ := envelope.New("test", []byte(payload)).Marshal()
+		src.Publish(data)
+	}
+	waitIdle(t, src)
+	sub.Close()
+
+	// Обе ревизии сохранены по порядку: при обгоне первая была бы отклонена как устаревшая
+	if store.saves != 2 {
+		t.Errorf("Expected 2 saves, got %d", store.saves)
+	}
+	if order, _ := c.Get("r-1"); order == nil || order.TrackNumber != "T2" {
+		t.Errorf("Expected r-1 at T2, got %+v", order)
+	}
+	if got := retriedInPlace.Value() - retried; got != 2 {
+		t.Errorf("Expected 2 retries, got %d", got)
+	}
+	if got := redeliveryLeft.Value() - unacked; got != 0 {
+		t.Errorf("Expected no redelivery, got %d", got)
+	}
+}
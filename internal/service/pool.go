@@ -0,0 +1,83 @@
+package service
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// WorkerPool выполняет задачи на фиксированном числе воркеров. Задачи с одинаковым
+// ключом попадают к одному воркеру и выполняются в порядке поступления, задачи с разными
+// ключами - параллельно. У каждого воркера ограниченная очередь: Submit блокируется,
+// пока в ней нет места, и так передает обратное давление подписке.
+type WorkerPool struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+
+	// mu защищает очереди от закрытия, пока Submit в них пишет: callback брокера
+	// может быть еще в Submit, когда подписка уже закрыта
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	p := &WorkerPool{queues: make([]chan func(), workers)}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), queueSize)
+		p.wg.Add(1)
+		go p.run(p.queues[i])
+	}
+	return p
+}
+
+func (p *WorkerPool) run(queue <-chan func()) {
+	defer p.wg.Done()
+	for job := range queue {
+		job()
+	}
+}
+
+// Submit ставит задачу в очередь воркера, которому принадлежит ключ. После Close
+// задача не принимается и Submit возвращает false.
+func (p *WorkerPool) Submit(key string, job func()) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	// Блокировка чтения держится и на время ожидания места: воркеры продолжают
+	// разбирать очередь, а Close ждет, пока отправка завершится
+	p.queues[p.partition(key)] <- job
+	return true
+}
+
+func (p *WorkerPool) partition(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+// Workers возвращает число воркеров.
+func (p *WorkerPool) Workers() int {
+	return len(p.queues)
+}
+
+// Close перестает принимать задачи и дожидается выполнения уже поставленных.
+// Повторный вызов ничего не делает.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, q := range p.queues {
+			close(q)
+		}
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"order-service/internal/cache"
+	"order-service/internal/envelope"
+	"order-service/internal/events"
+	"order-service/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_KeepsOrderPerKey(t *testing.T) {
+	pool := NewWorkerPool(4, 8)
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("order-%d", i%10)
+		seq := i
+		pool.Submit(key, func() {
+			mu.Lock()
+			got[key] = append(got[key], seq)
+			mu.Unlock()
+		})
+	}
+	pool.Close()
+
+	for key, seqs := range got {
+		if len(seqs) != 10 {
+			t.Errorf("%s: expected 10 jobs, got %d", key, len(seqs))
+		}
+		for i := 1; i < len(seqs); i++ {
+			if seqs[i] < seqs[i-1] {
+				t.Errorf("%s: jobs out of order: %v", key, seqs)
+				break
+			}
+		}
+	}
+}
+
+func TestWorkerPool_Backpressure(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	release := make(chan struct{})
+
+	pool.Submit("a", func() { <-release }) // выполняется
+	pool.Submit("a", func() {})            // ждет в очереди
+
+	submitted := make(chan struct{})
+	go func() {
+		pool.Submit("a", func() {})
+		close(submitted)
+	}()
+
+	select {
+	case <-submitted:
+		t.Fatal("Submit should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-submitted:
+	case <-time.After(time.Second):
+		t.Fatal("Submit should unblock once the worker frees the queue")
+	}
+	pool.Close()
+}
+
+// Callback брокера может оказаться в Submit одновременно с Close: задача либо
+// выполняется, либо отклоняется, но паники из-за закрытой очереди нет.
+func TestWorkerPool_SubmitDuringClose(t *testing.T) {
+	pool := NewWorkerPool(2, 1)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted, ran := 0, 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ok := pool.Submit(fmt.Sprintf("order-%d", j), func() {
+					mu.Lock()
+					ran++
+					mu.Unlock()
+				})
+				if ok {
+					mu.Lock()
+					accepted++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	pool.Close()
+	wg.Wait()
+
+	if accepted != ran {
+		t.Errorf("accepted %d jobs, ran %d", accepted, ran)
+	}
+	if pool.Submit("late", func() {}) {
+		t.Error("Submit after Close should be rejected")
+	}
+	pool.Close()
+}
+
+func TestOrderKey(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{`{"order_uid":"abc","track_number":"T"}`, "abc"},
+		{`{"order_uid":123}`, "123"},
+		{`{"track_number":"T"}`, ""},
+		{`[1,2]`, ""},
+	}
+	for _, tt := range tests {
+		if got := orderKey([]byte(tt.payload)); got != tt.want {
+			t.Errorf("orderKey(%s) = %q, want %q", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	if !isTransient(fmt.Errorf("wrapped: %w", &transientError{fmt.Errorf("db down")})) {
+		t.Error("Expected wrapped transient error to be detected")
+	}
+	if isTransient(fmt.Errorf("order_uid is required")) {
+		t.Error("Validation error must not be transient")
+	}
+}
+
+// slowStore имитирует задержку БД; безопасен для параллельного доступа.
+type slowStore struct {
+	mu      sync.Mutex
+	latency time.Duration
+	states  map[string]models.OrderState
+}
+
+func (s *slowStore) SaveOrder(ctx context.Context, order *models.Order, outbox ...events.Message) error {
+	time.Sleep(s.latency)
+	s.mu.Lock()
+	s.states[order.OrderUID] = models.OrderState{Status: order.Status, Version: order.Version, ContentHash: order.ContentHash}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *slowStore) GetOrderState(ctx context.Context, uid string) (models.OrderState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, found := s.states[uid]
+	return state, found, nil
+}
+
+// BenchmarkWorkerPool показывает пропускную способность конвейера в зависимости от числа воркеров
+// при задержке сохранения 200µs: go test -bench WorkerPool ./internal/service
+func BenchmarkWorkerPool(b *testing.B) {
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	defer slog.SetDefault(prev)
+
+	for _, workers := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			store := &slowStore{latency: 200 * time.Microsecond, states: map[string]models.OrderState{}}
+			sub := NewSubscriber(nil, store, cache.New())
+			pool := NewWorkerPool(workers, 64)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Каждое сообщение - новая ревизия одного из 64 заказов
+				data := fmt.Sprintf(`{"order_uid":"order-%d","track_number":"T","payment":{"transaction":"txn"},"version":%d}`, i%64, i+1)
+				env := envelope.New("bench", []byte(data))
+				pool.Submit(orderKey(env.Payload), func() { sub.process(context.Background(), env) })
+			}
+			pool.Close()
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"order-service/internal/broker"
+	"order-service/internal/cache"
+	"order-service/internal/envelope"
+	"order-service/internal/events"
+	"order-service/internal/logging"
+	"order-service/internal/models"
+	"sync"
+	"time"
+)
+
+var ErrReplayRunning = errors.New("replay is already running")
+
+// OrderReader читает сохраненный заказ целиком - для отчета об изменениях.
+type OrderReader interface {
+	GetOrder(uid string) (*models.Order, error)
+}
+
+// ReplayOptions - параметры повторной обработки. Повтор заканчивается после Limit
+// сообщений или если новых сообщений нет дольше IdleTimeout.
+type ReplayOptions struct {
+	From        broker.Position
+	Apply       bool
+	Limit       int
+	IdleTimeout time.Duration
+}
+
+// Итог обработки одного сообщения при повторе
+const (
+	ReplayCreate    = "create"
+	ReplayUpdate    = "update"
+	ReplayDuplicate = "duplicate"
+	ReplayRejected  = "rejected"
+)
+
+type ReplayResult struct {
+	Sequence uint64   `json:"sequence"`
+	OrderUID string   `json:"order_uid,omitempty"`
+	Action   string   `json:"action"`
+	Changes  []string `json:"changes,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type ReplayReport struct {
+	Apply      bool           `json:"apply"`
+	Messages   int            `json:"messages"`
+	Created    int            `json:"created"`
+	Updated    int            `json:"updated"`
+	Duplicates int            `json:"duplicates"`
+	Rejected   int            `json:"rejected"`
+	Results    []ReplayResult `json:"results"`
+}
+
+// Replayer перечитывает историю брокера временной подпиской и прогоняет сообщения
+// через текущий конвейер подписчика. В режиме dry-run записи идут в слой поверх
+// хранилища, поэтому несколько сообщений об одном заказе оцениваются последовательно.
+type Replayer struct {
+	sub    *Subscriber
+	source broker.Replayer
+	orders OrderReader
+	// Одновременно выполняется один повтор
+	mu sync.Mutex
+}
+
+func NewReplayer(sub *Subscriber, source broker.Replayer, orders OrderReader) *Replayer {
+	return &Replayer{sub: sub, source: source, orders: orders}
+}
+
+func (r *Replayer) Run(ctx context.Context, opts ReplayOptions) (*ReplayReport, error) {
+	if !r.mu.TryLock() {
+		return nil, ErrReplayRunning
+	}
+	defer r.mu.Unlock()
+
+	if opts.Limit <= 0 {
+		opts.Limit = 1000
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 2 * time.Second
+	}
+
+	target := r.sub
+	var overlay *dryRunStore
+	if !opts.Apply {
+		overlay = &dryRunStore{base: r.sub.repo, states: map[string]models.OrderState{}, orders: map[string]*models.Order{}}
+		target = &Subscriber{repo: overlay, cache: cache.New()}
+		target.strict.Store(r.sub.strict.Load())
+		target.strictDecoding.Store(r.sub.strictDecoding.Load())
+	}
+
+	messages := make(chan broker.Message)
+	done := make(chan struct{})
+	stop, err := r.source.Replay(ctx, opts.From, func(msg broker.Message) {
+		select {
+		case messages <- msg:
+		case <-done:
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start replay: %v", err)
+	}
+	// Сначала отпускаем заблокированный callback, затем закрываем подписку
+	defer func() {
+		close(done)
+		if err := stop(); err != nil {
+			slog.ErrorContext(ctx, "Error closing replay subscription", "error", err)
+		}
+	}()
+
+	slog.InfoContext(ctx, "Replay started", "from_sequence", opts.From.Sequence, "from_time", opts.From.Time, "apply", opts.Apply)
+	report := &ReplayReport{Apply: opts.Apply, Results: []ReplayResult{}}
+	idle := time.NewTimer(opts.IdleTimeout)
+	defer idle.Stop()
+
+	for report.Messages < opts.Limit {
+		select {
+		case msg := <-messages:
+			result := r.replayOne(ctx, target, overlay, msg)
+			report.add(result)
+			idle.Reset(opts.IdleTimeout)
+		case <-idle.C:
+			slog.InfoContext(ctx, "Replay finished", "messages", report.Messages)
+			return report, nil
+		case <-ctx.Done():
+			return report, ctx.Err()
+		}
+	}
+	slog.InfoContext(ctx, "Replay stopped at limit", "messages", report.Messages)
+	return report, nil
+}
+
+func (r *Replayer) replayOne(ctx context.Context, target *Subscriber, overlay *dryRunStore, msg broker.Message) ReplayResult {
+	result := ReplayResult{Sequence: msg.Sequence()}
+	ctx = logging.WithCorrelationID(ctx, fmt.Sprintf("replay:%s:%d", msg.Subject(), msg.Sequence()))
+
+	env, err := envelope.Parse(msg.Data())
+	if err != nil {
+		result.Action, result.Error = ReplayRejected, err.Error()
+		return result
+	}
+	result.OrderUID = orderKey(env.Payload)
+
+	p, err := target.prepare(ctx, env)
+	if err != nil {
+		result.Action, result.Error = ReplayRejected, err.Error()
+		return result
+	}
+	if p.duplicate {
+		result.Action = ReplayDuplicate
+		return result
+	}
+
+	result.Action = ReplayCreate
+	if p.found {
+		result.Action = ReplayUpdate
+		current, err := r.current(overlay, p.order.OrderUID)
+		if err != nil {
+			result.Action, result.Error = ReplayRejected, err.Error()
+			return result
+		}
+		result.Changes = models.ChangedFields(current, p.order)
+	}
+
+	// В dry-run у подписчика нет эмиттера, события записываются только при apply
+	p.changed = result.Changes
+	if err := target.commit(ctx, p); err != nil {
+		result.Action, result.Error = ReplayRejected, err.Error()
+	}
+	return result
+}
+
+// current возвращает заказ в том виде, в каком он есть до применения сообщения.
+func (r *Replayer) current(overlay *dryRunStore, uid string) (*models.Order, error) {
+	if overlay != nil {
+		if order, ok := overlay.order(uid); ok {
+			return order, nil
+		}
+	}
+	if order, ok := r.sub.cache.Get(uid); ok {
+		return order, nil
+	}
+	order, err := r.orders.GetOrder(uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return order, err
+}
+
+func (rep *ReplayReport) add(result ReplayResult) {
+	rep.Messages++
+	switch result.Action {
+	case ReplayCreate:
+		rep.Created++
+	case ReplayUpdate:
+		rep.Updated++
+	case ReplayDuplicate:
+		rep.Duplicates++
+	case ReplayRejected:
+		rep.Rejected++
+	}
+	rep.Results = append(rep.Results, result)
+}
+
+// dryRunStore запоминает записи в памяти, а чтения без записей передает хранилищу.
+type dryRunStore struct {
+	base   OrderStore
+	mu     sync.Mutex
+	states map[string]models.OrderState
+	orders map[string]*models.Order
+}
+
+func (d *dryRunStore) SaveOrder(ctx context.Context, order *models.Order, outbox ...events.Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.states[order.OrderUID] = models.OrderState{Status: order.Status, Version: order.Version, ContentHash: order.ContentHash}
+	d.orders[order.OrderUID] = order
+	return nil
+}
+
+func (d *dryRunStore) GetOrderState(ctx context.Context, uid string) (models.OrderState, bool, error) {
+	d.mu.Lock()
+	state, ok := d.states[uid]
+	d.mu.Unlock()
+	if ok {
+		return state, true, nil
+	}
+	return d.base.GetOrderState(ctx, uid)
+}
+
+func (d *dryRunStore) order(uid string) (*models.Order, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	order, ok := d.orders[uid]
+	return order, ok
+}
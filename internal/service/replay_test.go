@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"order-service/internal/broker"
+	"order-service/internal/cache"
+	"order-service/internal/envelope"
+	"order-service/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReplayer_DryRunAndApply(t *testing.T) {
+	src := broker.NewMemory("orders")
+	store := &flakyStore{states: map[string]models.OrderState{}, orders: map[string]*models.Order{}}
+	c := cache.New()
+	sub := NewSubscriber(src, store, c)
+	// Строгая проверка отклоняет заказ без имени получателя
+	sub.SetStrictValidation(true)
+	if err := sub.Start(context.Background(), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, payload := range []string{
+		`{"order_uid":"a","track_number":"T","delivery":{"name":"N"},"items":[{"rid":"r"}],"payment":{"transaction":"t"},"version":1}`,
+		`{"order_uid":"b","track_number":"T","items":[{"rid":"r"}],"payment":{"transaction":"t"}}`,
+		`{"order_uid":"a","track_number":"T2","delivery":{"name":"N"},"items":[{"rid":"r"}],"payment":{"transaction":"t"},"version":2}`,
+	} {
+		data, _ := envelope.New("test", []byte(payload)).Marshal()
+		src.Publish(data)
+	}
+	src.Publish([]byte(`not json`))
+	waitIdle(t, src)
+	if store.saves != 2 {
+		t.Fatalf("Expected 2 saves before replay, got %d", store.saves)
+	}
+
+	// Ошибку валидации исправили
+	sub.SetStrictValidation(false)
+	replayer := NewReplayer(sub, src, store)
+	opts := ReplayOptions{From: broker.Position{Sequence: 2}, IdleTimeout: 50 * time.Millisecond}
+
+	report, err := replayer.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ReplayResult{
+		{Sequence: 2, OrderUID: "b", Action: ReplayCreate},
+		{Sequence: 3, OrderUID: "a", Action: ReplayDuplicate},
+		{Sequence: 4, Action: ReplayRejected},
+	}
+	if len(report.Results) != len(want) {
+		t.Fatalf("Expected %d results, got %+v", len(want), report.Results)
+	}
+	for i, w := range want {
+		got := report.Results[i]
+		if got.Sequence != w.Sequence || got.OrderUID != w.OrderUID || got.Action != w.Action {
+			t.Errorf("Result %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if report.Created != 1 || report.Duplicates != 1 || report.Rejected != 1 {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	// Dry-run ничего не пишет
+	if store.saves != 2 {
+		t.Errorf("Dry run must not save, got %d saves", store.saves)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("Dry run must not touch the cache")
+	}
+
+	opts.Apply = true
+	report, err = replayer.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 1 || store.saves != 3 {
+		t.Errorf("Expected b to be created on apply, report %+v, saves %d", report, store.saves)
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("Applied order should be cached")
+	}
+	sub.Close()
+}
+
+func TestReplayer_ReportsChangedFields(t *testing.T) {
+	src := broker.NewMemory("orders")
+	store := &flakyStore{states: map[string]models.OrderState{}, orders: map[string]*models.Order{}}
+	sub := NewSubscriber(src, store, cache.New())
+
+	// Заказ уже сохранен; повтор приносит два следующих изменения
+	sub.process(context.Background(), envelope.New("test", []byte(`{"order_uid":"a","track_number":"T","payment":{"transaction":"t"},"version":1}`)))
+	for _, payload := range []string{
+		`{"order_uid":"a","track_number":"T2","payment":{"transaction":"t"},"version":2}`,
+		`{"order_uid":"a","track_number":"T2","payment":{"transaction":"t"},"version":3,"status":2}`,
+	} {
+		data, _ := envelope.New("test", []byte(payload)).Marshal()
+		src.Publish(data)
+	}
+
+	report, err := NewReplayer(sub, src, store).Run(context.Background(), ReplayOptions{IdleTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 2 {
+		t.Fatalf("Expected 2 updates, got %+v", report)
+	}
+	// Второе сообщение сравнивается с результатом первого, а не с БД
+	if got := report.Results[0].Changes; !reflect.DeepEqual(got, []string{"track_number", "version"}) {
+		t.Errorf("First update changes = %v", got)
+	}
+	if got := report.Results[1].Changes; !reflect.DeepEqual(got, []string{"status", "version"}) {
+		t.Errorf("Second update changes = %v", got)
+	}
+}
@@ -0,0 +1,506 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"order-service/internal/broker"
+	"order-service/internal/cache"
+	"order-service/internal/decoder"
+	"order-service/internal/envelope"
+	"order-service/internal/events"
+	"order-service/internal/logging"
+	"order-service/internal/metrics"
+	"order-service/internal/models"
+	"order-service/internal/tracing"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OrderStore - хранилище, в которое подписчик сохраняет принятые заказы.
+// События outbox записываются в одной транзакции с заказом.
+type OrderStore interface {
+	SaveOrder(ctx context.Context, order *models.Order, outbox ...events.Message) error
+	GetOrderState(ctx context.Context, uid string) (models.OrderState, bool, error)
+}
+
+// EventQueue - хранилище, которое записывает события без изменения заказа.
+type EventQueue interface {
+	EnqueueEvents(ctx context.Context, messages ...events.Message) error
+}
+
+var (
+	messagesProcessed = metrics.NewCounter("order_messages_processed_total", "Orders saved to the database and cache.")
+	duplicatesSkipped = metrics.NewCounter("order_messages_deduplicated_total", "Messages skipped because their content equals the stored order.")
+	staleRejected     = metrics.NewCounter("order_messages_stale_total", "Messages rejected because their version is older than the stored order.")
+	redeliveryLeft    = metrics.NewCounter("order_messages_unacked_total", "Messages left unacknowledged after a transient failure for redelivery.")
+	retriedInPlace    = metrics.NewCounter("order_messages_retried_total", "Transient failures retried in place by the worker.")
+)
+
+// Пауза между повторами временной ошибки удваивается до retryMaxBackoff
+const (
+	retryBackoff    = 100 * time.Millisecond
+	retryMaxBackoff = 10 * time.Second
+)
+
+// transientError - временный сбой (БД недоступна и т.п.): воркер повторяет сообщение,
+// пока оно не обработается. Остальные ошибки повтором не исправить.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+func isTransient(err error) bool {
+	var t *transientError
+	return errors.As(err, &t)
+}
+
+// Subscriber принимает заказы из брокера: разбор, валидация, сохранение в БД и кэш.
+type Subscriber struct {
+	source broker.MessageSource
+	repo   OrderStore
+	cache  *cache.Cache
+	strict atomic.Bool
+	// Строгий разбор без приведения типов - для продюсеров, которые уже исправлены
+	strictDecoding atomic.Bool
+	// Доменные события в outbox; nil - не записываются
+	events *events.Emitter
+
+	pool *WorkerPool
+	// Закрывается в Close и прерывает повторы
+	done chan struct{}
+}
+
+func NewSubscriber(source broker.MessageSource, repo OrderStore, cache *cache.Cache) *Subscriber {
+	return &Subscriber{
+		source: source,
+		repo:   repo,
+		cache:  cache,
+		done:   make(chan struct{}),
+	}
+}
+
+// InFlightLimit - сколько сообщений брокеру стоит выдавать без подтверждения, чтобы
+// все они поместились в очереди воркеров.
+func InFlightLimit(workers, queueSize int) int {
+	return max(workers, 1) * (max(queueSize, 1) + 1)
+}
+
+// SetStrictValidation включает дополнительные проверки заказа; безопасно вызывать на лету.
+func (s *Subscriber) SetStrictValidation(strict bool) {
+	s.strict.Store(strict)
+}
+
+// SetStrictDecoding отключает мягкое приведение типов при разборе сообщений.
+func (s *Subscriber) SetStrictDecoding(strict bool) {
+	s.strictDecoding.Store(strict)
+}
+
+// SetEventEmitter включает запись доменных событий в outbox. Вызывается до Start.
+func (s *Subscriber) SetEventEmitter(emitter *events.Emitter) {
+	s.events = emitter
+}
+
+// Start подписывается на источник. При workers = 0 сообщения обрабатываются
+// последовательно в callback подписки.
+func (s *Subscriber) Start(ctx context.Context, workers, queueSize int) error {
+	if workers > 0 {
+		s.pool = NewWorkerPool(workers, queueSize)
+	}
+	return s.source.Subscribe(ctx, s.dispatch)
+}
+
+// Close закрывает подписку и дожидается обработки сообщений, уже принятых воркерами.
+func (s *Subscriber) Close() {
+	close(s.done)
+	if err := s.source.Close(); err != nil {
+		slog.Error("Error closing message source", "error", err)
+	}
+	if s.pool != nil {
+		s.pool.Close()
+	}
+}
+
+// dispatch разбирает конверт в callback подписки и передает сообщение воркеру
+// по order_uid, чтобы обновления одного заказа обрабатывались по порядку.
+func (s *Subscriber) dispatch(msg broker.Message) {
+	ctx := logging.WithCorrelationID(context.Background(), fmt.Sprintf("%s:%d", msg.Subject(), msg.Sequence()))
+
+	env, err := envelope.Parse(msg.Data())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error parsing message envelope", "error", err, "size", len(msg.Data()))
+		s.ack(ctx, msg, err)
+		return
+	}
+
+	if s.pool == nil {
+		s.handle(ctx, msg, env)
+		return
+	}
+	if !s.pool.Submit(orderKey(env.Payload), func() { s.handle(ctx, msg, env) }) {
+		// Подписчик закрывается: без подтверждения брокер доставит сообщение повторно
+		slog.WarnContext(ctx, "Worker pool is closed, message left unacknowledged")
+	}
+}
+
+// handle обрабатывает сообщение и подтверждает его. Подтверждение отправляется воркером
+// после обработки, поэтому внутри одного заказа сообщения подтверждаются по порядку.
+func (s *Subscriber) handle(ctx context.Context, msg broker.Message, env *envelope.Envelope) {
+	ctx = tracing.Extract(ctx, env.Headers)
+	if env.MessageID != "" {
+		ctx = logging.WithCorrelationID(ctx, env.MessageID)
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "nats.receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", s.source.System()),
+			attribute.String("messaging.destination.name", msg.Subject()),
+			attribute.Int64("messaging.nats.sequence", int64(msg.Sequence())),
+			attribute.Bool("messaging.redelivered", msg.Redelivered()),
+			attribute.String("messaging.message.id", env.MessageID),
+			attribute.String("messaging.producer", env.Producer),
+			attribute.Int("messaging.schema_version", env.SchemaVersion),
+		))
+	err := s.processWithRetry(ctx, msg, env)
+	tracing.End(span, err)
+	s.ack(ctx, msg, err)
+}
+
+// processWithRetry повторяет сообщение после временной ошибки на месте, не отпуская его
+// брокеру: иначе следующие сообщения того же заказа обогнали бы повторную доставку.
+// Воркер (его раздел заказов) ждет вместе с сообщением. При остановке сообщение
+// возвращается брокеру с временной ошибкой.
+func (s *Subscriber) processWithRetry(ctx context.Context, msg broker.Message, env *envelope.Envelope) error {
+	err := s.process(ctx, env)
+	for backoff := retryBackoff; isTransient(err); backoff = min(backoff*2, retryMaxBackoff) {
+		retriedInPlace.Inc()
+		slog.WarnContext(ctx, "Transient error, retrying message", "error", err, "backoff", backoff)
+		// Брокер не должен счесть сообщение потерянным, пока идут повторы
+		if p, ok := msg.(broker.Progresser); ok {
+			if err := p.InProgress(); err != nil {
+				slog.ErrorContext(ctx, "Error extending message ack wait", "error", err)
+			}
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-s.done:
+			timer.Stop()
+			return err
+		}
+		err = s.process(ctx, env)
+	}
+	return err
+}
+
+func (s *Subscriber) ack(ctx context.Context, msg broker.Message, err error) {
+	if isTransient(err) {
+		redeliveryLeft.Inc()
+		slog.WarnContext(ctx, "Message left unacknowledged for redelivery", "error", err)
+		if err := msg.Nak(); err != nil {
+			slog.ErrorContext(ctx, "Error rejecting message", "error", err)
+		}
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		slog.ErrorContext(ctx, "Error acknowledging message", "error", err)
+	}
+}
+
+// orderKey достает order_uid для выбора воркера, не разбирая заказ целиком.
+// Нераспознанная нагрузка попадает в общий раздел с пустым ключом.
+func orderKey(payload []byte) string {
+	var probe struct {
+		OrderUID json.RawMessage `json:"order_uid"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return ""
+	}
+	return strings.Trim(string(probe.OrderUID), `"`)
+}
+
+func (s *Subscriber) process(ctx context.Context, env *envelope.Envelope) error {
+	err := s.apply(ctx, env)
+	var stale *models.ErrStaleVersion
+	switch {
+	case err == nil:
+	case errors.As(err, &stale):
+		staleRejected.Inc()
+	case !isTransient(err):
+		s.emitRejected(ctx, env, err)
+	}
+	return err
+}
+
+// apply проверяет и сохраняет заказ из сообщения.
+func (s *Subscriber) apply(ctx context.Context, env *envelope.Envelope) error {
+	for attempt := 1; ; attempt++ {
+		p, err := s.prepare(ctx, env)
+		if err != nil {
+			return err
+		}
+
+		// Повторная доставка или повторная отправка того же заказа
+		if p.duplicate {
+			duplicatesSkipped.Inc()
+			slog.InfoContext(ctx, "Duplicate order skipped", "order_uid", p.order.OrderUID, "content_hash", p.order.ContentHash)
+			return nil
+		}
+
+		// Изменения считаются до записи, пока кэш хранит предыдущую версию
+		if s.events != nil && p.found {
+			p.changed = models.ChangedFields(s.previousOrder(p.order.OrderUID), p.order)
+		}
+
+		if err := s.commit(ctx, p); err != nil {
+			// Статус изменился между проверкой и транзакцией - сообщение проверяется
+			// заново по новому состоянию заказа
+			var illegal *models.ErrIllegalTransition
+			if errors.As(err, &illegal) && attempt == 1 {
+				slog.InfoContext(ctx, "Order status changed concurrently, rechecking", "order_uid", p.order.OrderUID)
+				continue
+			}
+			return err
+		}
+
+		messagesProcessed.Inc()
+		slog.InfoContext(ctx, "Order processed successfully", "order_uid", p.order.OrderUID)
+		return nil
+	}
+}
+
+// plan - заказ из сообщения, проверенный относительно сохраненного состояния и готовый к записи.
+type plan struct {
+	order *models.Order
+	// found - заказ уже сохранен, previous - его состояние
+	found     bool
+	previous  models.OrderState
+	duplicate bool
+	// changed - изменившиеся поля для события
+	changed []string
+}
+
+// prepare выполняет все проверки сообщения, ничего не записывая.
+func (s *Subscriber) prepare(ctx context.Context, env *envelope.Envelope) (*plan, error) {
+	slog.DebugContext(ctx, "Received message",
+		"producer", env.Producer, "schema_version", env.SchemaVersion, "legacy", env.Legacy, "payload", env.Payload)
+
+	_, span := tracing.Tracer().Start(ctx, "order.unmarshal")
+	order, err := s.decode(env)
+	tracing.End(span, err)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error unmarshaling message", "error", err, "size", len(env.Payload))
+		return nil, err
+	}
+	if len(order.Warnings) > 0 {
+		slog.WarnContext(ctx, "Order decoded with type coercions", "order_uid", order.OrderUID, "warnings", order.Warnings)
+	}
+
+	// Валидация данных
+	_, span = tracing.Tracer().Start(ctx, "order.validate")
+	err = s.validateOrder(order)
+	tracing.End(span, err)
+	if err != nil {
+		slog.WarnContext(ctx, "Invalid order data", "error", err, "order_uid", order.OrderUID)
+		return nil, err
+	}
+
+	// Нормализация статусов позиций
+	for i := range order.Items {
+		if order.Items[i].Status == "" {
+			order.Items[i].Status = models.ItemPending
+		}
+	}
+	order.Fulfillment = models.DeriveFulfillment(order.Items)
+
+	// Хеш считается до подстановки текущего статуса - от содержимого сообщения
+	order.ContentHash, err = contentHash(order)
+	if err != nil {
+		return nil, err
+	}
+
+	state, found, err := s.repo.GetOrderState(ctx, order.OrderUID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error loading order state", "error", err, "order_uid", order.OrderUID)
+		return nil, &transientError{fmt.Errorf("failed to load order state: %v", err)}
+	}
+	p := &plan{order: order, found: found, previous: state}
+
+	if found && state.ContentHash == order.ContentHash {
+		p.duplicate = true
+		return p, nil
+	}
+
+	// Более старая ревизия или обновление без версии не должны перезаписать сохраненный заказ
+	if found {
+		if err := order.CheckVersion(state); err != nil {
+			slog.WarnContext(ctx, "Rejected stale order version", "error", err, "order_uid", order.OrderUID)
+			return nil, err
+		}
+	}
+
+	// Проверка перехода статуса
+	if err := checkTransition(order, state.Status, found); err != nil {
+		slog.WarnContext(ctx, "Rejected order status change", "error", err, "order_uid", order.OrderUID)
+		return nil, err
+	}
+	return p, nil
+}
+
+// commit сохраняет подготовленный заказ в БД и кэш.
+func (s *Subscriber) commit(ctx context.Context, p *plan) error {
+	order := p.order
+
+	var outbox []events.Message
+	if s.events != nil {
+		msg, err := s.events.Encode(events.Committed(ctx, order, p.found, p.previous.Status, p.changed))
+		if err != nil {
+			slog.ErrorContext(ctx, "Error encoding order event", "error", err, "order_uid", order.OrderUID)
+		} else {
+			outbox = append(outbox, msg)
+		}
+	}
+
+	order.UpdatedAt = time.Now().UTC()
+
+	// Сохранение в БД вместе с событием
+	if err := s.repo.SaveOrder(ctx, order, outbox...); err != nil {
+		// Более новая версия успела записаться между проверкой и транзакцией
+		var stale *models.ErrStaleVersion
+		if errors.As(err, &stale) {
+			slog.WarnContext(ctx, "Rejected stale order version", "error", err, "order_uid", order.OrderUID)
+			return err
+		}
+		// Статус заказа в транзакции не допускает перехода из сообщения
+		var illegal *models.ErrIllegalTransition
+		if errors.As(err, &illegal) {
+			slog.WarnContext(ctx, "Rejected order status change", "error", err, "order_uid", order.OrderUID)
+			return err
+		}
+		slog.ErrorContext(ctx, "Error saving order to DB", "error", err, "order_uid", order.OrderUID)
+		return &transientError{err}
+	}
+
+	// Сохранение в кэш
+	_, span := tracing.Tracer().Start(ctx, "cache.Set")
+	if !s.cache.Set(order) {
+		slog.DebugContext(ctx, "Cache already holds a newer version", "order_uid", order.OrderUID)
+	}
+	span.End()
+	return nil
+}
+
+// previousOrder возвращает сохраненный заказ из кэша или, если хранилище умеет, из БД.
+// nil - заказ недоступен, и изменившимися считаются все поля.
+func (s *Subscriber) previousOrder(uid string) *models.Order {
+	if order, ok := s.cache.Get(uid); ok {
+		return order
+	}
+	if reader, ok := s.repo.(OrderReader); ok {
+		if order, err := reader.GetOrder(uid); err == nil {
+			return order
+		}
+	}
+	return nil
+}
+
+// emitRejected записывает событие об отклоненном сообщении. Заказ мог не разобраться,
+// поэтому order_uid и версия берутся из нагрузки напрямую.
+func (s *Subscriber) emitRejected(ctx context.Context, env *envelope.Envelope, err error) {
+	queue, ok := s.repo.(EventQueue)
+	if s.events == nil || !ok {
+		return
+	}
+	var probe struct {
+		Version int64 `json:"version"`
+	}
+	json.Unmarshal(env.Payload, &probe)
+	msg, encErr := s.events.Encode(events.Rejected(ctx, orderKey(env.Payload), probe.Version, err))
+	if encErr == nil {
+		encErr = queue.EnqueueEvents(ctx, msg)
+	}
+	if encErr != nil {
+		slog.ErrorContext(ctx, "Error recording rejected order event", "error", encErr)
+	}
+}
+
+// decode приводит полезную нагрузку к текущей версии схемы и разбирает заказ.
+func (s *Subscriber) decode(env *envelope.Envelope) (*models.Order, error) {
+	payload, err := envelope.Upgrade(env.SchemaVersion, env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.Decode(payload, s.strictDecoding.Load())
+}
+
+func (s *Subscriber) validateOrder(order *models.Order) error {
+	if order.OrderUID == "" {
+		return fmt.Errorf("order_uid is required")
+	}
+	if order.TrackNumber == "" {
+		return fmt.Errorf("track_number is required")
+	}
+	if order.Payment.Transaction == "" {
+		return fmt.Errorf("payment transaction is required")
+	}
+	if order.Status != models.StatusUnknown && !order.Status.Valid() {
+		return fmt.Errorf("unknown order status %d", order.Status)
+	}
+	if !s.strict.Load() {
+		return nil
+	}
+
+	// Строгий режим
+	if order.Delivery.Name == "" {
+		return fmt.Errorf("delivery name is required")
+	}
+	if len(order.Items) == 0 {
+		return fmt.Errorf("order must contain at least one item")
+	}
+	p := order.Payment
+	if p.Amount != p.GoodsTotal+p.DeliveryCost+p.CustomFee {
+		return fmt.Errorf("payment amount %d does not match goods_total + delivery_cost + custom_fee (%d)",
+			p.Amount, p.GoodsTotal+p.DeliveryCost+p.CustomFee)
+	}
+	return nil
+}
+
+// contentHash - SHA-256 канонического JSON заказа. Порядок полей задан структурой,
+// поэтому сообщения, отличающиеся только форматированием или порядком ключей, совпадают.
+func contentHash(order *models.Order) (string, error) {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash order: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkTransition сверяет статус из сообщения с текущим статусом заказа по графу
+// переходов. Сообщение без статуса сохраняет текущий статус (created для нового заказа).
+func checkTransition(order *models.Order, current models.OrderStatus, found bool) error {
+	if order.Status == models.StatusUnknown {
+		order.Status = models.StatusCreated
+		if found {
+			order.Status = current
+		}
+		return nil
+	}
+
+	if found && !current.CanTransitionTo(order.Status) {
+		return &models.ErrIllegalTransition{OrderUID: order.OrderUID, From: current, To: order.Status}
+	}
+	return nil
+}
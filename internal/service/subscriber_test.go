@@ -0,0 +1,439 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"order-service/internal/broker"
+	"order-service/internal/cache"
+	"order-service/internal/envelope"
+	"order-service/internal/events"
+	"order-service/internal/models"
+	"order-service/internal/tracing"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSubscriber_ValidateOrder(t *testing.T) {
+	sub := &Subscriber{}
+
+	tests := []struct {
+		name    string
+		order   *models.Order
+		wantErr bool
+	}{
+		{
+			name: "Valid order",
+			order: &models.Order{
+				OrderUID:    "test-123",
+				TrackNumber: "TRACK-123",
+				Payment:     models.Payment{Transaction: "txn-123"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Missing order UID",
+			order: &models.Order{
+				TrackNumber: "TRACK-123",
+				Payment:     models.Payment{Transaction: "txn-123"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Missing track number",
+			order: &models.Order{
+				OrderUID: "test-123",
+				Payment:  models.Payment{Transaction: "txn-123"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sub.validateOrder(tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubscriber_ValidateOrderStrict(t *testing.T) {
+	sub := &Subscriber{}
+	sub.SetStrictValidation(true)
+
+	valid := models.Order{
+		OrderUID:    "test-123",
+		TrackNumber: "TRACK-123",
+		Delivery:    models.Delivery{Name: "Test Testov"},
+		Payment:     models.Payment{Transaction: "txn-123", Amount: 1817, GoodsTotal: 317, DeliveryCost: 1500},
+		Items:       []models.Item{{ChrtID: 9934930}},
+	}
+
+	noItems := valid
+	noItems.Items = nil
+
+	badAmount := valid
+	badAmount.Payment.Amount = 1
+
+	tests := []struct {
+		name    string
+		order   models.Order
+		wantErr bool
+	}{
+		{name: "Valid order", order: valid, wantErr: false},
+		{name: "No items", order: noItems, wantErr: true},
+		{name: "Amount mismatch", order: badAmount, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sub.validateOrder(&tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type fakeStore struct {
+	saved    []*models.Order
+	ctxs     []context.Context
+	statuses map[string]models.OrderStatus
+	hashes   map[string]string
+	versions map[string]int64
+	outbox   []events.Message
+	// beforeSave имитирует запись, сделанную между проверкой и транзакцией
+	beforeSave func()
+}
+
+func (s *fakeStore) SaveOrder(ctx context.Context, order *models.Order, outbox ...events.Message) error {
+	if s.beforeSave != nil {
+		s.beforeSave()
+		s.beforeSave = nil
+	}
+	// Как и репозиторий, проверяет переход по сохраненному статусу
+	if current, ok := s.statuses[order.OrderUID]; ok && !current.CanTransitionTo(order.Status) {
+		return &models.ErrIllegalTransition{OrderUID: order.OrderUID, From: current, To: order.Status}
+	}
+	s.saved = append(s.saved, order)
+	s.outbox = append(s.outbox, outbox...)
+	s.ctxs = append(s.ctxs, ctx)
+	if s.statuses == nil {
+		s.statuses = map[string]models.OrderStatus{}
+	}
+	if s.hashes == nil {
+		s.hashes = map[string]string{}
+	}
+	s.statuses[order.OrderUID] = order.Status
+	if s.versions == nil {
+		s.versions = map[string]int64{}
+	}
+	if order.Version > s.versions[order.OrderUID] {
+		s.versions[order.OrderUID] = order.Version
+	}
+	s.hashes[order.OrderUID] = order.ContentHash
+	return nil
+}
+
+func (s *fakeStore) EnqueueEvents(ctx context.Context, messages ...events.Message) error {
+	s.outbox = append(s.outbox, messages...)
+	return nil
+}
+
+func (s *fakeStore) GetOrderState(ctx context.Context, uid string) (models.OrderState, bool, error) {
+	status, found := s.statuses[uid]
+	return models.OrderState{Status: status, Version: s.versions[uid], ContentHash: s.hashes[uid]}, found, nil
+}
+
+func TestSubscriber_SpanTree(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	store := &fakeStore{}
+
+	// Span публикации, как в cmd/publisher
+	ctx, publish := tracing.Tracer().Start(context.Background(), "nats.publish")
+	env := envelope.New("test", []byte(`{"order_uid":"test-123","track_number":"TRACK-123","payment":{"transaction":"txn-123"}}`))
+	tracing.Inject(ctx, env.Headers)
+	data, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish.End()
+
+	src := broker.NewMemory("orders")
+	sub := NewSubscriber(src, store, cache.New())
+	if err := sub.Start(context.Background(), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	src.Publish(data)
+	waitIdle(t, src)
+	sub.Close()
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = s
+	}
+
+	receive, ok := spans["nats.receive"]
+	if !ok {
+		t.Fatal("nats.receive span not recorded")
+	}
+	if receive.Parent.SpanID() != publish.SpanContext().SpanID() {
+		t.Errorf("nats.receive should be a child of nats.publish")
+	}
+
+	for _, name := range []string{"order.unmarshal", "order.validate", "cache.Set"} {
+		s, ok := spans[name]
+		if !ok {
+			t.Errorf("%s span not recorded", name)
+			continue
+		}
+		if s.Parent.SpanID() != receive.SpanContext.SpanID() {
+			t.Errorf("%s should be a child of nats.receive", name)
+		}
+	}
+
+	if len(store.ctxs) != 1 {
+		t.Fatalf("Expected 1 saved order, got %d", len(store.ctxs))
+	}
+	if got := trace.SpanContextFromContext(store.ctxs[0]).SpanID(); got != receive.SpanContext.SpanID() {
+		t.Errorf("SaveOrder should run inside nats.receive span")
+	}
+}
+
+func TestSubscriber_StatusTransitions(t *testing.T) {
+	store := &fakeStore{}
+	sub := NewSubscriber(nil, store, cache.New())
+
+	version := 0
+	send := func(status models.OrderStatus) error {
+		version++
+		data := fmt.Sprintf(`{"order_uid":"test-123","track_number":"TRACK-123","payment":{"transaction":"txn-123"},"status":%d,"version":%d}`, status, version)
+		return sub.process(context.Background(), envelope.New("test", []byte(data)))
+	}
+
+	steps := []struct {
+		status  models.OrderStatus
+		wantErr bool
+	}{
+		{models.StatusUnknown, false}, // новый заказ без статуса -> created
+		{models.StatusPaid, false},    // created -> paid
+		{models.StatusShipped, true},  // paid -> shipped: пропущена сборка
+		{models.StatusAssembling, false},
+		{models.StatusUnknown, false}, // без статуса - текущий сохраняется
+		{models.StatusShipped, false},
+		{models.StatusCancelled, true}, // отгруженный заказ нельзя отменить
+		{models.StatusDelivered, false},
+		{models.StatusCreated, true}, // назад по графу нельзя
+		{models.StatusReturned, false},
+		{models.OrderStatus(42), true}, // неизвестный статус
+	}
+
+	for i, step := range steps {
+		err := send(step.status)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("step %d (%s): error = %v, wantErr %v", i, step.status, err, step.wantErr)
+		}
+	}
+
+	if got := store.statuses["test-123"]; got != models.StatusReturned {
+		t.Errorf("Expected final status returned, got %s", got)
+	}
+	if store.saved[3].Status != models.StatusAssembling {
+		t.Errorf("Order without status should keep assembling, got %s", store.saved[3].Status)
+	}
+}
+
+func TestSubscriber_ConcurrentStatusChange(t *testing.T) {
+	store := &fakeStore{}
+	sub := NewSubscriber(nil, store, cache.New())
+	send := func(data string) error {
+		return sub.process(context.Background(), envelope.New("test", []byte(data)))
+	}
+	cancel := func() { store.statuses["race-1"] = models.StatusCancelled }
+
+	if err := send(`{"order_uid":"race-1","track_number":"TRACK-1","payment":{"transaction":"txn-1"},"version":1}`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Заказ отменили после проверки: переход created -> paid больше недопустим
+	store.beforeSave = cancel
+	err := send(`{"order_uid":"race-1","track_number":"TRACK-1","payment":{"transaction":"txn-1"},"status":2,"version":2}`)
+	var illegal *models.ErrIllegalTransition
+	if !errors.As(err, &illegal) || illegal.From != models.StatusCancelled {
+		t.Fatalf("Expected illegal transition from cancelled, got %v", err)
+	}
+
+	// Сообщение без статуса перепроверяется и сохраняет новый статус
+	store.statuses["race-1"] = models.StatusCreated
+	store.beforeSave = cancel
+	if err := send(`{"order_uid":"race-1","track_number":"TRACK-2","payment":{"transaction":"txn-1"},"version":2}`); err != nil {
+		t.Fatalf("Expected status-less update to be retried, got %v", err)
+	}
+	if last := store.saved[len(store.saved)-1]; last.TrackNumber != "TRACK-2" || last.Status != models.StatusCancelled {
+		t.Errorf("Expected TRACK-2 saved as cancelled, got %s %s", last.TrackNumber, last.Status)
+	}
+}
+
+func TestSubscriber_Deduplication(t *testing.T) {
+	store := &fakeStore{}
+	c := cache.New()
+	sub := NewSubscriber(nil, store, c)
+
+	send := func(data string) {
+		t.Helper()
+		if err := sub.process(context.Background(), envelope.New("test", []byte(data))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := duplicatesSkipped.Value()
+	send(`{"order_uid":"dup-1","track_number":"TRACK-1","payment":{"transaction":"txn-1"},"items":[{"rid":"r1","status":202}],"version":1}`)
+	// Тот же заказ с другим порядком ключей и форматированием
+	send(`{"version": 1, "payment": {"transaction": "txn-1"}, "items": [{"status": 202, "rid": "r1"}], "track_number": "TRACK-1", "order_uid": "dup-1"}`)
+
+	if len(store.saved) != 1 {
+		t.Fatalf("Expected duplicate to be skipped, saved %d times", len(store.saved))
+	}
+	if got := duplicatesSkipped.Value() - before; got != 1 {
+		t.Errorf("Expected 1 dedup hit, got %d", got)
+	}
+	if store.saved[0].ContentHash == "" {
+		t.Error("Expected content hash to be stored with the order")
+	}
+
+	// Измененный заказ сохраняется
+	send(`{"order_uid":"dup-1","track_number":"TRACK-2","payment":{"transaction":"txn-1"},"items":[{"rid":"r1","status":202}],"version":2}`)
+	if len(store.saved) != 2 {
+		t.Fatalf("Expected changed order to be saved, saved %d times", len(store.saved))
+	}
+	if cached, _ := c.Get("dup-1"); cached == nil || cached.TrackNumber != "TRACK-2" {
+		t.Errorf("Expected cache to hold the updated order, got %+v", cached)
+	}
+}
+
+func TestSubscriber_Versions(t *testing.T) {
+	store := &fakeStore{}
+	c := cache.New()
+	sub := NewSubscriber(nil, store, c)
+
+	send := func(version int, track string) error {
+		data := fmt.Sprintf(`{"order_uid":"ver-1","track_number":%q,"payment":{"transaction":"txn-1"},"version":%d}`, track, version)
+		return sub.process(context.Background(), envelope.New("test", []byte(data)))
+	}
+
+	steps := []struct {
+		version int
+		track   string
+		wantErr bool
+	}{
+		{2, "TRACK-2", false},
+		{1, "TRACK-1", true}, // пришла позже более новой - отклоняется
+		{3, "TRACK-3", false},
+		{3, "TRACK-3b", true}, // та же версия с другим содержимым - порядок не определить
+		{0, "TRACK-0", true},  // обновление без версии упорядочить нельзя
+	}
+
+	for i, step := range steps {
+		err := send(step.version, step.track)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("step %d (version %d): error = %v, wantErr %v", i, step.version, err, step.wantErr)
+		}
+		if step.wantErr {
+			var stale *models.ErrStaleVersion
+			if !errors.As(err, &stale) {
+				t.Errorf("step %d: expected ErrStaleVersion, got %v", i, err)
+			}
+		}
+	}
+
+	if len(store.saved) != 2 {
+		t.Errorf("Expected 2 saves, got %d", len(store.saved))
+	}
+	if store.versions["ver-1"] != 3 {
+		t.Errorf("Expected stored version 3, got %d", store.versions["ver-1"])
+	}
+	if cached, _ := c.Get("ver-1"); cached == nil || cached.TrackNumber != "TRACK-3" {
+		t.Errorf("Cache should keep the first message of version 3, got %+v", cached)
+	}
+}
+
+func TestSubscriber_Events(t *testing.T) {
+	store := &fakeStore{}
+	sub := NewSubscriber(nil, store, cache.New())
+	sub.SetEventEmitter(events.NewEmitter(events.Subjects{
+		Created: "e.created", Updated: "e.updated", StatusChanged: "e.status", Rejected: "e.rejected",
+	}))
+
+	send := func(data string) {
+		sub.process(context.Background(), envelope.New("test", []byte(data)))
+	}
+	send(`{"order_uid":"ev-1","track_number":"TRACK-1","payment":{"transaction":"txn-1"},"version":1}`)
+	send(`{"order_uid":"ev-1","track_number":"TRACK-1","payment":{"transaction":"txn-1"},"version":1}`) // дубликат - без события
+	send(`{"order_uid":"ev-1","track_number":"TRACK-2","payment":{"transaction":"txn-1"},"version":2}`)
+	send(`{"order_uid":"ev-1","track_number":"TRACK-2","payment":{"transaction":"txn-1"},"version":3,"status":2}`)
+	send(`{"order_uid":"ev-1","track_number":"TRACK-2","payment":{"transaction":"txn-1"},"version":1}`) // устаревшая версия - без события
+	send(`{"order_uid":"ev-2","payment":{"transaction":"txn-2"},"version":7}`)
+
+	want := []struct {
+		subject string
+		typ     events.Type
+		uid     string
+		rev     int64
+		changed []string
+	}{
+		{"e.created", events.OrderCreated, "ev-1", 1, nil},
+		{"e.updated", events.OrderUpdated, "ev-1", 2, []string{"track_number", "version"}},
+		{"e.status", events.OrderStatusChanged, "ev-1", 3, []string{"status", "version"}},
+		{"e.rejected", events.OrderRejected, "ev-2", 7, nil},
+	}
+	// События попадают в outbox вместе с заказом
+	if len(store.outbox) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(store.outbox))
+	}
+	got := make([]events.Event, len(store.outbox))
+	for i, w := range want {
+		msg := store.outbox[i]
+		if err := json.Unmarshal(msg.Data, &got[i]); err != nil {
+			t.Fatal(err)
+		}
+		e := got[i]
+		if msg.Subject != w.subject || msg.EventID != e.ID || e.Type != w.typ || e.OrderUID != w.uid || e.Revision != w.rev ||
+			!slices.Equal(e.ChangedFields, w.changed) {
+			t.Errorf("event %d = %s %+v, want %+v", i, msg.Subject, e, w)
+		}
+	}
+	if got[2].PreviousStatus != models.StatusCreated || got[2].Status != models.StatusPaid {
+		t.Errorf("status_changed = %v -> %v", got[2].PreviousStatus, got[2].Status)
+	}
+	if got[3].Reason != "track_number is required" {
+		t.Errorf("rejected reason = %q", got[3].Reason)
+	}
+}
+
+// waitIdle ждет, пока источник не останется без неподтвержденных сообщений.
+func waitIdle(t *testing.T, src *broker.Memory) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for src.Pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out with %d messages pending", src.Pending())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
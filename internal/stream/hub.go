@@ -0,0 +1,130 @@
+package stream
+
+import (
+	"order-service/internal/models"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Update - изменение заказа с номером для возобновления потока (Last-Event-ID).
+type Update struct {
+	ID    uint64
+	Order *models.Order
+}
+
+// Filter отбирает заказы по клиенту, заказу и статусу; пустые поля не ограничивают.
+type Filter struct {
+	CustomerID string
+	OrderUID   string
+	Statuses   []models.OrderStatus
+}
+
+func (f Filter) Match(order *models.Order) bool {
+	if f.CustomerID != "" && order.CustomerID != f.CustomerID {
+		return false
+	}
+	if f.OrderUID != "" && order.OrderUID != f.OrderUID {
+		return false
+	}
+	return len(f.Statuses) == 0 || slices.Contains(f.Statuses, order.Status)
+}
+
+// Hub раздает изменения заказов подписчикам и хранит последние size изменений
+// в кольцевом буфере для возобновления после переподключения.
+type Hub struct {
+	mu     sync.Mutex
+	ring   []Update
+	start  int
+	nextID uint64
+	subs   map[*Subscription]struct{}
+	// Размер очереди подписчика; медленный подписчик отключается
+	queue int
+}
+
+func NewHub(size, queue int) *Hub {
+	return &Hub{
+		ring: make([]Update, 0, max(size, 1)),
+		// Номера растут и между перезапусками, поэтому старый Last-Event-ID
+		// распознается как пропуск, а не как будущее событие
+		nextID: uint64(time.Now().UnixMilli()) << 10,
+		subs:   make(map[*Subscription]struct{}),
+		queue:  max(queue, 1),
+	}
+}
+
+// Publish добавляет изменение в буфер и рассылает его подписчикам.
+func (h *Hub) Publish(order *models.Order) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	update := Update{ID: h.nextID, Order: order}
+	if len(h.ring) < cap(h.ring) {
+		h.ring = append(h.ring, update)
+	} else {
+		h.ring[h.start] = update
+		h.start = (h.start + 1) % len(h.ring)
+	}
+
+	for sub := range h.subs {
+		if !sub.filter.Match(order) {
+			continue
+		}
+		select {
+		case sub.updates <- update:
+		default:
+			// Клиент не успевает читать - отключаем, он переподключится с Last-Event-ID
+			h.drop(sub)
+		}
+	}
+}
+
+// Subscription - подписка на изменения. Канал Updates закрывается при отключении.
+type Subscription struct {
+	hub     *Hub
+	filter  Filter
+	updates chan Update
+}
+
+func (s *Subscription) Updates() <-chan Update { return s.updates }
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+func (h *Hub) drop(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.updates)
+	}
+}
+
+// Subscribe подписывает на изменения. При resume возвращает подходящие изменения
+// после lastID из буфера; complete = false, если часть изменений уже вытеснена
+// и клиенту нужно перечитать состояние целиком.
+func (h *Hub) Subscribe(filter Filter, lastID uint64, resume bool) (sub *Subscription, backlog []Update, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub = &Subscription{hub: h, filter: filter, updates: make(chan Update, h.queue)}
+	h.subs[sub] = struct{}{}
+	if !resume {
+		return sub, nil, true
+	}
+
+	oldest := h.nextID + 1
+	if len(h.ring) > 0 {
+		oldest = h.ring[h.start].ID
+	}
+	complete = lastID+1 >= oldest && lastID <= h.nextID
+	for i := range h.ring {
+		update := h.ring[(h.start+i)%len(h.ring)]
+		if update.ID > lastID && filter.Match(update.Order) {
+			backlog = append(backlog, update)
+		}
+	}
+	return sub, backlog, complete
+}
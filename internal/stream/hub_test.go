@@ -0,0 +1,66 @@
+package stream
+
+import (
+	"order-service/internal/models"
+	"testing"
+)
+
+func order(uid, customer string, status models.OrderStatus) *models.Order {
+	return &models.Order{OrderUID: uid, CustomerID: customer, Status: status}
+}
+
+func TestHub_FilterAndResume(t *testing.T) {
+	hub := NewHub(3, 8)
+
+	live, _, _ := hub.Subscribe(Filter{CustomerID: "alice"}, 0, false)
+	defer live.Close()
+
+	hub.Publish(order("o1", "alice", models.StatusCreated))
+	hub.Publish(order("o2", "bob", models.StatusCreated))
+	hub.Publish(order("o1", "alice", models.StatusPaid))
+
+	first := <-live.Updates()
+	second := <-live.Updates()
+	if first.Order.Status != models.StatusCreated || second.Order.Status != models.StatusPaid || second.ID <= first.ID {
+		t.Fatalf("live updates = %+v, %+v", first, second)
+	}
+	if len(live.Updates()) != 0 {
+		t.Error("update of another customer was delivered")
+	}
+
+	// Возобновление после первого изменения: остальные - из буфера
+	sub, backlog, complete := hub.Subscribe(Filter{}, first.ID, true)
+	sub.Close()
+	if !complete || len(backlog) != 2 || backlog[1].ID != second.ID {
+		t.Errorf("resume: complete=%v, backlog=%+v", complete, backlog)
+	}
+
+	// Буфер на 3 изменения: первое вытеснено
+	hub.Publish(order("o3", "carol", models.StatusShipped))
+	sub, backlog, complete = hub.Subscribe(Filter{Statuses: []models.OrderStatus{models.StatusShipped}}, first.ID-1, true)
+	sub.Close()
+	if complete || len(backlog) != 1 || backlog[0].Order.OrderUID != "o3" {
+		t.Errorf("evicted resume: complete=%v, backlog=%+v", complete, backlog)
+	}
+
+	// ID из предыдущего запуска сервиса
+	sub, _, complete = hub.Subscribe(Filter{}, 42, true)
+	sub.Close()
+	if complete {
+		t.Error("Expected resume from an unknown ID to be incomplete")
+	}
+}
+
+func TestHub_DropsSlowSubscriber(t *testing.T) {
+	hub := NewHub(10, 1)
+	sub, _, _ := hub.Subscribe(Filter{}, 0, false)
+
+	hub.Publish(order("o1", "", models.StatusCreated))
+	hub.Publish(order("o2", "", models.StatusCreated))
+
+	<-sub.Updates()
+	if _, ok := <-sub.Updates(); ok {
+		t.Fatal("Expected slow subscriber to be disconnected")
+	}
+	sub.Close() // повторное закрытие безопасно
+}
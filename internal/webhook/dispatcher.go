@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"order-service/internal/envelope"
+	"order-service/internal/events"
+	"order-service/internal/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	deliveriesSucceeded = metrics.NewCounter("order_webhook_deliveries_succeeded_total", "Webhook deliveries acknowledged by the receiver.")
+	deliveriesFailed    = metrics.NewCounter("order_webhook_deliveries_failed_total", "Webhook deliveries abandoned after the last attempt.")
+	attemptsFailed      = metrics.NewCounter("order_webhook_attempts_failed_total", "Webhook delivery attempts that failed.")
+)
+
+type Options struct {
+	// Interval - как часто проверять доставки, время которых пришло
+	Interval    time.Duration
+	BatchSize   int
+	Timeout     time.Duration
+	MaxAttempts int
+	// Задержка перед повтором: Backoff * 2^(попытка-1), не больше MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Concurrency - сколько запросов одновременно отправляется одному получателю
+	Concurrency int
+}
+
+// Dispatcher регистрирует вебхуки и доставляет им события. Как events.Publisher
+// он получает события от relay outbox и ставит доставки в очередь.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	opts   Options
+	now    func() time.Time
+	// checkAddr проверяет адрес получателя перед каждым соединением
+	checkAddr func(netip.Addr) error
+}
+
+func NewDispatcher(store Store, opts Options) *Dispatcher {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Minute
+	}
+	opts.MaxBackoff = max(opts.MaxBackoff, opts.Backoff)
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	d := &Dispatcher{
+		store:     store,
+		opts:      opts,
+		now:       time.Now,
+		checkAddr: publicAddr,
+	}
+	d.client = d.newClient(opts.Timeout)
+	return d
+}
+
+// Register проверяет и сохраняет вебхук.
+func (d *Dispatcher) Register(ctx context.Context, w *Webhook) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	u, _ := url.Parse(w.URL)
+	if err := d.checkHost(u.Hostname()); err != nil {
+		return &ValidationError{fmt.Sprintf("url must point to a public host: %v", err)}
+	}
+	w.ID = envelope.NewID()
+	w.CreatedAt = d.now().UTC()
+	return d.store.CreateWebhook(ctx, w)
+}
+
+// Deliveries возвращает журнал доставок вебхука.
+func (d *Dispatcher) Deliveries(ctx context.Context, webhookID string, limit int) ([]*Delivery, error) {
+	if _, err := d.store.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return d.store.ListDeliveries(ctx, webhookID, limit)
+}
+
+// Publish ставит событие в очередь доставки всем подходящим вебхукам.
+func (d *Dispatcher) Publish(subject string, data []byte) error {
+	ctx := context.Background()
+	var event struct {
+		ID   string      `json:"id"`
+		Type events.Type `json:"type"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("failed to decode event: %v", err)
+	}
+
+	hooks, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	now := d.now().UTC()
+	var deliveries []*Delivery
+	for _, w := range hooks {
+		if !w.Accepts(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, &Delivery{
+			WebhookID:     w.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Status:        StatusPending,
+			Payload:       data,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return d.store.CreateDeliveries(ctx, deliveries)
+}
+
+// Run отправляет доставки до отмены ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.DeliverDue(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// DeliverDue делает по одной попытке для каждой доставки, время которой пришло.
+// Получатели обслуживаются параллельно, каждый - не больше чем opts.Concurrency
+// запросами сразу, поэтому медленный получатель не задерживает доставки остальным.
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	// Аренда с запасом на таймаут всех запросов пачки
+	lease := d.opts.Timeout*time.Duration(d.opts.BatchSize) + time.Minute
+	deliveries, err := d.store.ClaimDeliveries(ctx, d.opts.BatchSize, lease)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error loading webhook deliveries", "error", err)
+		return
+	}
+
+	byWebhook := map[string][]*Delivery{}
+	for _, delivery := range deliveries {
+		byWebhook[delivery.WebhookID] = append(byWebhook[delivery.WebhookID], delivery)
+	}
+	var wg sync.WaitGroup
+	for _, queue := range byWebhook {
+		jobs := make(chan *Delivery, len(queue))
+		for _, delivery := range queue {
+			jobs <- delivery
+		}
+		close(jobs)
+		for range min(d.opts.Concurrency, len(queue)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for delivery := range jobs {
+					d.attempt(ctx, delivery)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	number := delivery.AttemptCount + 1
+	start := d.now()
+	code, err := d.send(ctx, delivery)
+	attempt := Attempt{
+		Number:     number,
+		At:         start.UTC(),
+		StatusCode: code,
+		DurationMs: d.now().Sub(start).Milliseconds(),
+	}
+
+	status, next := StatusSucceeded, time.Time{}
+	switch {
+	case err == nil:
+		deliveriesSucceeded.Inc()
+	case number >= d.opts.MaxAttempts:
+		attempt.Error = err.Error()
+		attemptsFailed.Inc()
+		deliveriesFailed.Inc()
+		status = StatusFailed
+		slog.WarnContext(ctx, "Webhook delivery failed", "webhook_id", delivery.WebhookID, "event_id", delivery.EventID, "attempts", number, "error", err)
+	default:
+		attempt.Error = err.Error()
+		attemptsFailed.Inc()
+		status, next = StatusPending, d.now().Add(d.backoff(number))
+	}
+
+	if err := d.store.RecordAttempt(ctx, delivery.ID, attempt, status, next); err != nil {
+		slog.ErrorContext(ctx, "Error recording webhook attempt", "error", err, "delivery_id", delivery.ID)
+	}
+}
+
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.opts.Backoff
+	for i := 1; i < attempt && delay < d.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.opts.MaxBackoff)
+}
+
+// send отправляет событие; успех - любой ответ 2xx.
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, error) {
+	timestamp := d.now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "order-service-webhooks")
+	req.Header.Set(HeaderID, delivery.WebhookID)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEventType, string(delivery.EventType))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,335 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"order-service/internal/events"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore - Store в памяти с той же семантикой выборки, что и в Postgres.
+type memStore struct {
+	mu         sync.Mutex
+	hooks      []*Webhook
+	deliveries []*Delivery
+	now        func() time.Time
+}
+
+func (m *memStore) CreateWebhook(ctx context.Context, w *Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, w)
+	return nil
+}
+
+func (m *memStore) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Webhook(nil), m.hooks...), nil
+}
+
+func (m *memStore) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, w := range m.hooks {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memStore) CreateDeliveries(ctx context.Context, deliveries []*Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range deliveries {
+		// Как UNIQUE (webhook_id, event_id)
+		if slices.ContainsFunc(m.deliveries, func(e *Delivery) bool { return e.WebhookID == d.WebhookID && e.EventID == d.EventID }) {
+			continue
+		}
+		d.ID = int64(len(m.deliveries) + 1)
+		m.deliveries = append(m.deliveries, d)
+	}
+	return nil
+}
+
+func (m *memStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []*Delivery
+	for _, d := range m.deliveries {
+		if len(due) == limit {
+			break
+		}
+		if d.Status != StatusPending || d.NextAttemptAt.After(m.now()) {
+			continue
+		}
+		d.NextAttemptAt = m.now().Add(lease)
+		for _, w := range m.hooks {
+			if w.ID == d.WebhookID {
+				d.URL, d.Secret = w.URL, w.Secret
+			}
+		}
+		claimed := *d
+		due = append(due, &claimed)
+	}
+	return due, nil
+}
+
+func (m *memStore) RecordAttempt(ctx context.Context, id int64, attempt Attempt, status string, next time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.deliveries[id-1]
+	d.Attempts = append(d.Attempts, attempt)
+	d.AttemptCount++
+	d.Status, d.NextAttemptAt = status, next
+	return nil
+}
+
+func (m *memStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*Delivery
+	for _, d := range m.deliveries {
+		if d.WebhookID == webhookID {
+			list = append(list, d)
+		}
+	}
+	return list, nil
+}
+
+func TestDispatcher_DeliversSignedEventsWithRetries(t *testing.T) {
+	const secret = "partner-secret-0123456789"
+
+	var mu sync.Mutex
+	var received []string
+	failures := 2
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if !Verify(secret, ts, body, r.Header.Get(HeaderSignature)) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, r.Header.Get(HeaderEventType))
+	}))
+	defer receiver.Close()
+
+	clock := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+	store := &memStore{now: func() time.Time { return clock }}
+	d := NewDispatcher(store, Options{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 3 * time.Second})
+	d.now = store.now
+	d.checkAddr = allowAll
+	ctx := context.Background()
+
+	hook := &Webhook{URL: receiver.URL, Events: []events.Type{events.OrderStatusChanged}, Secret: secret}
+	if err := d.Register(ctx, hook); err != nil {
+		t.Fatal(err)
+	}
+	d.Publish("orders.events.created", []byte(`{"id":"e1","type":"order.created"}`))
+	d.Publish("orders.events.status_changed", []byte(`{"id":"e2","type":"order.status_changed"}`))
+	// Повторная передача события relay не создает вторую доставку
+	d.Publish("orders.events.status_changed", []byte(`{"id":"e2","type":"order.status_changed"}`))
+	if len(store.deliveries) != 1 {
+		t.Fatalf("Expected only the filtered event to be queued once, got %d deliveries", len(store.deliveries))
+	}
+
+	// Две неудачи: задержки 1s и 2s
+	d.DeliverDue(ctx)
+	if next := store.deliveries[0].NextAttemptAt; !next.Equal(clock.Add(time.Second)) {
+		t.Errorf("Expected retry after 1s, got %v", next.Sub(clock))
+	}
+	d.DeliverDue(ctx) // время еще не пришло
+	clock = clock.Add(time.Second)
+	d.DeliverDue(ctx)
+	if next := store.deliveries[0].NextAttemptAt; !next.Equal(clock.Add(2 * time.Second)) {
+		t.Errorf("Expected retry after 2s, got %v", next.Sub(clock))
+	}
+	clock = clock.Add(2 * time.Second)
+	d.DeliverDue(ctx)
+
+	deliveries, err := d.Deliveries(ctx, hook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := deliveries[0]
+	if got.Status != StatusSucceeded || len(got.Attempts) != 3 {
+		t.Fatalf("delivery = %s with %d attempts", got.Status, len(got.Attempts))
+	}
+	if got.Attempts[0].StatusCode != http.StatusServiceUnavailable || got.Attempts[0].Error == "" || got.Attempts[2].Error != "" {
+		t.Errorf("attempts = %+v", got.Attempts)
+	}
+	if len(received) != 1 || received[0] != "order.status_changed" {
+		t.Errorf("received = %v", received)
+	}
+
+	if _, err := d.Deliveries(ctx, "missing", 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	clock := time.Now()
+	store := &memStore{now: func() time.Time { return clock }}
+	d := NewDispatcher(store, Options{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Second})
+	d.now = store.now
+	d.checkAddr = allowAll
+	ctx := context.Background()
+
+	d.Register(ctx, &Webhook{URL: receiver.URL, Secret: "0123456789abcdef"})
+	d.Publish("orders.events.created", []byte(`{"id":"e1","type":"order.created"}`))
+	for range 5 {
+		d.DeliverDue(ctx)
+		clock = clock.Add(time.Second)
+	}
+
+	got := store.deliveries[0]
+	if got.Status != StatusFailed || len(got.Attempts) != 3 {
+		t.Errorf("delivery = %s with %d attempts, want failed after 3", got.Status, len(got.Attempts))
+	}
+}
+
+func TestWebhook_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    Webhook
+		wantErr bool
+	}{
+		{"valid", Webhook{URL: "https://partner.example/hook", Secret: "0123456789abcdef"}, false},
+		{"with filter", Webhook{URL: "http://localhost:9000", Secret: "0123456789abcdef", Events: []events.Type{events.OrderCreated}}, false},
+		{"relative url", Webhook{URL: "/hook", Secret: "0123456789abcdef"}, true},
+		{"ftp url", Webhook{URL: "ftp://partner.example", Secret: "0123456789abcdef"}, true},
+		{"short secret", Webhook{URL: "https://partner.example/hook", Secret: "short"}, true},
+		{"unknown event", Webhook{URL: "https://partner.example/hook", Secret: "0123456789abcdef", Events: []events.Type{"order.deleted"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hook.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// allowAll снимает запрет внутренних адресов для получателей httptest на 127.0.0.1.
+func allowAll(netip.Addr) error { return nil }
+
+func TestDispatcher_RejectsInternalTargets(t *testing.T) {
+	hits := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer receiver.Close()
+
+	store := &memStore{now: time.Now}
+	d := NewDispatcher(store, Options{MaxAttempts: 3})
+	ctx := context.Background()
+
+	for _, url := range []string{receiver.URL, "http://169.254.169.254/latest/meta-data", "http://10.0.0.1/hook", "http://[::1]/hook", "http://localhost:9000/hook"} {
+		var invalid *ValidationError
+		if err := d.Register(ctx, &Webhook{URL: url, Secret: "0123456789abcdef"}); !errors.As(err, &invalid) {
+			t.Errorf("Register(%s): expected validation error, got %v", url, err)
+		}
+	}
+
+	// Адрес, который внутренним стал после регистрации, отклоняется при соединении
+	store.CreateWebhook(ctx, &Webhook{ID: "w1", URL: receiver.URL, Secret: "0123456789abcdef"})
+	d.Publish("orders.events.created", []byte(`{"id":"e1","type":"order.created"}`))
+	d.DeliverDue(ctx)
+
+	got := store.deliveries[0]
+	if hits != 0 || len(got.Attempts) != 1 || !strings.Contains(got.Attempts[0].Error, "not public") {
+		t.Errorf("Expected dial to be refused, hits %d, attempts %+v", hits, got.Attempts)
+	}
+}
+
+func TestDispatcher_DoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { followed = true }))
+	defer target.Close()
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer receiver.Close()
+
+	store := &memStore{now: time.Now}
+	d := NewDispatcher(store, Options{MaxAttempts: 3})
+	d.checkAddr = allowAll
+	ctx := context.Background()
+
+	d.Register(ctx, &Webhook{URL: receiver.URL, Secret: "0123456789abcdef"})
+	d.Publish("orders.events.created", []byte(`{"id":"e1","type":"order.created"}`))
+	d.DeliverDue(ctx)
+
+	got := store.deliveries[0]
+	if followed || got.Status != StatusPending || got.Attempts[0].StatusCode != http.StatusFound {
+		t.Errorf("Expected redirect to fail the attempt, followed %v, delivery %s %+v", followed, got.Status, got.Attempts)
+	}
+}
+
+func TestDispatcher_SlowReceiverDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer slow.Close()
+	fastDone := make(chan struct{}, 2)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fastDone <- struct{}{} }))
+	defer fast.Close()
+
+	store := &memStore{now: time.Now}
+	d := NewDispatcher(store, Options{Concurrency: 1})
+	d.checkAddr = allowAll
+	ctx := context.Background()
+
+	d.Register(ctx, &Webhook{URL: slow.URL, Secret: "0123456789abcdef"})
+	d.Register(ctx, &Webhook{URL: fast.URL, Secret: "0123456789abcdef"})
+	d.Publish("orders.events.created", []byte(`{"id":"e1","type":"order.created"}`))
+	d.Publish("orders.events.created", []byte(`{"id":"e2","type":"order.created"}`))
+
+	done := make(chan struct{})
+	go func() {
+		d.DeliverDue(ctx)
+		close(done)
+	}()
+	for range 2 {
+		select {
+		case <-fastDone:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Fast receiver waited for the slow one")
+		}
+	}
+	close(release)
+	<-done
+
+	if maxInFlight != 1 {
+		t.Errorf("Expected at most 1 concurrent request to the slow receiver, got %d", maxInFlight)
+	}
+}
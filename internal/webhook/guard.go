@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// publicAddr не пускает вебхуки во внутреннюю сеть сервиса: loopback, частные сети,
+// link-local (в том числе метаданные облака 169.254.169.254), multicast и 0.0.0.0.
+func publicAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return fmt.Errorf("address %s is not public", addr)
+	}
+	return nil
+}
+
+// checkHost отклоняет при регистрации явные внутренние адреса. Имена проверяются
+// при каждом соединении, после разрешения DNS (см. newClient).
+func (d *Dispatcher) checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("host %s is not public", host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return d.checkAddr(addr)
+	}
+	return nil
+}
+
+// newClient - HTTP-клиент доставок. Адрес проверяется в Control - после разрешения
+// DNS, перед соединением, поэтому имя, которое после регистрации стало указывать
+// на внутренний адрес, тоже не пройдет.
+func (d *Dispatcher) newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return d.checkAddr(addrPort.Addr())
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// Прокси из окружения обошел бы проверку адреса
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: timeout,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// Перенаправление не следует: ответ 3xx считается неудачной попыткой
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
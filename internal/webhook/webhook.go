@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"order-service/internal/events"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var ErrNotFound = errors.New("webhook not found")
+
+// ValidationError - некорректная регистрация вебхука.
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string { return "invalid webhook: " + e.Reason }
+
+// Webhook - подписка партнера на события заказов. Пустой Events - все типы.
+type Webhook struct {
+	ID        string        `json:"id"`
+	URL       string        `json:"url"`
+	Events    []events.Type `json:"events"`
+	Secret    string        `json:"-"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+func (w *Webhook) Accepts(typ events.Type) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, typ)
+}
+
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{"url must be an absolute http(s) URL"}
+	}
+	if len(w.Secret) < 16 {
+		return &ValidationError{"secret must be at least 16 characters"}
+	}
+	for _, typ := range w.Events {
+		if !slices.Contains(events.Types, typ) {
+			return &ValidationError{fmt.Sprintf("unknown event type %q", typ)}
+		}
+	}
+	return nil
+}
+
+// Статусы доставки
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Delivery - доставка одного события одному вебхуку.
+type Delivery struct {
+	ID            int64       `json:"id"`
+	WebhookID     string      `json:"webhook_id"`
+	EventID       string      `json:"event_id"`
+	EventType     events.Type `json:"event_type"`
+	Status        string      `json:"status"`
+	AttemptCount  int         `json:"attempt_count"`
+	NextAttemptAt time.Time   `json:"next_attempt_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Attempts      []Attempt   `json:"attempts"`
+
+	// Заполняются при выборке на отправку
+	Payload []byte `json:"-"`
+	URL     string `json:"-"`
+	Secret  string `json:"-"`
+}
+
+// Attempt - одна попытка доставки. StatusCode = 0, если ответа не было.
+type Attempt struct {
+	Number     int       `json:"number"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// Store хранит вебхуки и журнал доставок.
+type Store interface {
+	CreateWebhook(ctx context.Context, w *Webhook) error
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+	// GetWebhook возвращает ErrNotFound, если вебхука нет.
+	GetWebhook(ctx context.Context, id string) (*Webhook, error)
+	CreateDeliveries(ctx context.Context, deliveries []*Delivery) error
+	// ClaimDeliveries выбирает до limit доставок, время которых пришло, и откладывает
+	// их на lease, чтобы другие экземпляры сервиса не отправили их одновременно.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error)
+	// RecordAttempt сохраняет попытку и новое состояние доставки.
+	RecordAttempt(ctx context.Context, deliveryID int64, attempt Attempt, status string, next time.Time) error
+	// ListDeliveries возвращает последние доставки вебхука вместе с попытками.
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*Delivery, error)
+}
+
+// Заголовки запроса доставки
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderID        = "X-Webhook-ID"
+	HeaderEventID   = "X-Event-ID"
+	HeaderEventType = "X-Event-Type"
+)
+
+// Sign - подпись "sha256=<hex>" от HMAC-SHA256(secret, timestamp + "." + body).
+// Метка времени в подписи не дает повторить старый запрос.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись на стороне получателя.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -10,9 +10,22 @@ CREATE TABLE IF NOT EXISTS orders (
     sm_id INTEGER,
     date_created TIMESTAMP,
     oof_shard VARCHAR(50),
-    status INTEGER DEFAULT 1
+    status INTEGER DEFAULT 1,
+    -- Ревизия заказа от продюсера; запись с меньшей версией отклоняется
+    version BIGINT NOT NULL DEFAULT 0,
+    -- Приведения типов, выполненные при мягком разборе сообщения
+    warnings JSONB NOT NULL DEFAULT '[]',
+    -- SHA-256 канонического содержимого последнего принятого сообщения (дедупликация)
+    content_hash VARCHAR(64) NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+-- Базы, созданные до этих колонок: CREATE TABLE IF NOT EXISTS их не добавит
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS warnings JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
 CREATE TABLE IF NOT EXISTS deliveries (
     order_uid VARCHAR(255) PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE,
     name VARCHAR(255),
@@ -55,7 +68,81 @@ CREATE TABLE IF NOT EXISTS items (
     quantity INTEGER DEFAULT 1
 );
 
+-- История переходов статуса заказа (from_status = 0 для первой записи)
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) REFERENCES orders(order_uid) ON DELETE CASCADE,
+    from_status INTEGER NOT NULL DEFAULT 0,
+    to_status INTEGER NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Доменные события, записанные в одной транзакции с заказом; публикуются фоновым relay
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP
+);
+
+-- Вебхуки партнеров и журнал доставок (attempts - число сделанных попыток)
+CREATE TABLE IF NOT EXISTS webhooks (
+    id VARCHAR(64) PRIMARY KEY,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    secret TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id VARCHAR(64) NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL DEFAULT 0
+);
+
 CREATE INDEX IF NOT EXISTS idx_orders_uid ON orders(order_uid);
 CREATE INDEX IF NOT EXISTS idx_deliveries_order_uid ON deliveries(order_uid);
 CREATE INDEX IF NOT EXISTS idx_payments_order_uid ON payments(order_uid);
-CREATE INDEX IF NOT EXISTS idx_items_order_uid ON items(order_uid);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_items_order_uid ON items(order_uid);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_uid ON order_status_history(order_uid);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON outbox(sent_at) WHERE sent_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);
+-- Одна доставка события вебхуку; повторы, созданные до индекса, удаляются
+DELETE FROM webhook_deliveries a USING webhook_deliveries b
+WHERE a.webhook_id = b.webhook_id AND a.event_id = b.event_id AND a.id > b.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(webhook_id, event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery ON webhook_attempts(delivery_id);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id, order_uid);
+CREATE INDEX IF NOT EXISTS idx_orders_track_number ON orders(track_number);
+
+-- Перенумерация статусов заказа: до графа переходов 1 = In Store, 2 = In Transit,
+-- 3 = Delivered, 4 = Processing; теперь 1 created, 3 assembling, 4 shipped, 5 delivered.
+-- Заказы без истории статусов сохранены старыми кодами. Пересчитанным заказам пишется
+-- первая запись истории, поэтому повторный запуск их не трогает.
+WITH legacy AS (
+    UPDATE orders o SET status = CASE o.status WHEN 2 THEN 4 WHEN 3 THEN 5 WHEN 4 THEN 3 ELSE o.status END
+    WHERE NOT EXISTS (SELECT 1 FROM order_status_history h WHERE h.order_uid = o.order_uid)
+    RETURNING o.order_uid, o.status
+)
+INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at)
+SELECT order_uid, 0, status, NOW() FROM legacy;
@@ -6,13 +6,18 @@ import (
 	"log"
 	"math/rand"
 	"order-service/internal/config"
+	"order-service/internal/envelope"
+	"os"
 	"time"
 
 	"github.com/nats-io/stan.go"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	sc, err := stan.Connect(cfg.NATS.ClusterID, "publisher-100", stan.NatsURL(cfg.NATS.URL))
 	if err != nil {
@@ -50,9 +55,11 @@ func main() {
 	itemStatuses := []string{"pending", "processing", "shipped", "delivered"}
 
 	// Статусы заказов
-	orderStatuses := []int{1, 2, 3} // 1=In Store, 2=In Transit, 3=Delivered
+	orderStatuses := []int{1, 4, 5} // 1=Created, 4=Shipped, 5=Delivered
 
 	rand.Seed(time.Now().UnixNano())
+	// Ревизия заказов: повторный запуск обновляет уже сохраненные заказы
+	version := time.Now().UnixMilli()
 
 	// Создаем 100+ заказов
 	for i := 1; i <= 120; i++ {
@@ -81,11 +88,11 @@ func main() {
 			// Статус товара в зависимости от статуса заказа
 			var itemStatus string
 			switch orderStatus {
-			case 1: // In Store
+			case 1: // Created
 				itemStatus = itemStatuses[rand.Intn(2)]
-			case 2: // In Transit
+			case 4: // Shipped
 				itemStatus = "shipped"
-			case 3: // Delivered
+			case 5: // Delivered
 				itemStatus = "delivered"
 			}
 
@@ -146,13 +153,20 @@ func main() {
 			"date_created":       time.Now().Add(-time.Duration(i) * time.Hour).Format(time.RFC3339),
 			"oof_shard":          "1",
 			"status":             orderStatus,
+			"version":            version,
 		}
 
-		orderData, err := json.Marshal(order)
+		payload, err := json.Marshal(order)
 		if err != nil {
 			log.Printf("Error marshaling order %s: %v", orderUID, err)
 			continue
 		}
+		// Коды статусов v2: без конверта сообщение считалось бы схемой v1 и перенумеровывалось
+		orderData, err := envelope.New("publisher-100", payload).Marshal()
+		if err != nil {
+			log.Printf("Error wrapping order %s: %v", orderUID, err)
+			continue
+		}
 
 		if err := sc.Publish(cfg.NATS.Subject, orderData); err != nil {
 			log.Printf("Error publishing order %s: %v", orderUID, err)
@@ -168,6 +182,6 @@ func main() {
 
 	fmt.Println("🎉 Successfully published 120 orders with new structure!")
 	fmt.Println("📋 Order range: ORD-2024-001 to ORD-2024-120")
-	fmt.Println("🔄 Statuses: 1=In Store, 2=In Transit, 3=Delivered")
+	fmt.Println("🔄 Statuses: 1=Created, 4=Shipped, 5=Delivered")
 	fmt.Println("📦 Items now include quantity and proper statuses")
 }
@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"log"
 	"order-service/internal/config"
+	"os"
 	"time"
 
 	"github.com/nats-io/stan.go"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	sc, err := stan.Connect(cfg.NATS.ClusterID, "publisher-multi", stan.NatsURL(cfg.NATS.URL))
 	if err != nil {
@@ -98,5 +102,6 @@ func createOrder(orderUID, customerName, productName string, amount int, trackNu
 		"sm_id":              101,
 		"date_created":       time.Now().Format(time.RFC3339),
 		"oof_shard":          "1",
+		"version":            time.Now().UnixMilli(),
 	}
 }
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"order-service/api/client"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
 func main() {
-	url := "http://localhost:8080/orders/ORD-2024-001"
+	baseURL := "http://localhost:8080"
+	orderID := "ORD-2024-001"
 	var success, errors int64
 	var totalTime int64
 	var wg sync.WaitGroup
@@ -23,18 +26,24 @@ func main() {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			client := &http.Client{Timeout: 5 * time.Second}
+			api, err := client.NewClientWithResponses(baseURL,
+				client.WithHTTPClient(&http.Client{Timeout: 5 * time.Second}))
+			if err != nil {
+				panic(err)
+			}
 
 			for j := 0; j < requests/workers; j++ {
 				reqStart := time.Now()
-				resp, err := client.Get(url)
+				resp, err := api.GetOrderWithResponse(context.Background(), orderID, nil)
 				latency := time.Since(reqStart).Milliseconds()
 
 				atomic.AddInt64(&totalTime, latency)
 
-				if err == nil && resp.StatusCode == 200 {
+				if err == nil && resp.JSON200 != nil {
 					atomic.AddInt64(&success, 1)
-					resp.Body.Close()
+				} else if err == nil {
+					atomic.AddInt64(&errors, 1)
+					fmt.Printf("Error: HTTP %d\n", resp.StatusCode())
 				} else {
 					atomic.AddInt64(&errors, 1)
 					fmt.Printf("Error: %v\n", err)
@@ -49,7 +58,7 @@ func main() {
 	duration := time.Since(start).Seconds()
 
 	fmt.Printf("=== STRESS TEST RESULTS ===\n")
-	fmt.Printf("URL: %s\n", url)
+	fmt.Printf("URL: %s/orders/%s\n", baseURL, orderID)
 	fmt.Printf("Total requests: %d\n", requests)
 	fmt.Printf("Successful: %d\n", success)
 	fmt.Printf("Errors: %d\n", errors)
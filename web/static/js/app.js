@@ -1,10 +1,49 @@
 const ORDER_STATUSES = {
-    1: { text: "In Store", color: "blue" },
-    2: { text: "In Transit", color: "orange" }, 
-    3: { text: "Delivered", color: "green" },
-    4: { text: "Processing", color: "purple" }
+    1: { text: "Created", color: "blue" },
+    2: { text: "Paid", color: "blue" },
+    3: { text: "Assembling", color: "purple" },
+    4: { text: "Shipped", color: "orange" },
+    5: { text: "Delivered", color: "green" },
+    6: { text: "Cancelled", color: "red" },
+    7: { text: "Returned", color: "gray" }
 };
 
+// Поток изменений открытого заказа (/orders/stream)
+let orderStream = null;
+
+function watchOrder(orderId) {
+    stopWatching();
+    if (!window.EventSource) {
+        return;
+    }
+    orderStream = new EventSource(`/orders/stream?order_uid=${encodeURIComponent(orderId)}`);
+    orderStream.addEventListener('order', function(e) {
+        renderOrder(JSON.parse(e.data));
+        flashLive();
+    });
+    // Часть изменений пропущена - перечитываем заказ целиком
+    orderStream.addEventListener('reset', function() {
+        fetch(`/orders/${encodeURIComponent(orderId)}`)
+            .then(response => response.ok ? response.json() : null)
+            .then(order => order && renderOrder(order));
+    });
+}
+
+function stopWatching() {
+    if (orderStream) {
+        orderStream.close();
+        orderStream = null;
+    }
+}
+
+function flashLive() {
+    const card = document.querySelector('.order-card');
+    if (card) {
+        card.classList.add('order-updated');
+        setTimeout(() => card.classList.remove('order-updated'), 1500);
+    }
+}
+
 async function loadOrder() {
     const orderId = document.getElementById('orderIdInput').value.trim();
     const container = document.getElementById('order-container');
@@ -28,8 +67,10 @@ async function loadOrder() {
         }
         
         const order = await response.json();
+        watchOrder(orderId);
         renderOrder(order);
     } catch (error) {
+        stopWatching();
         container.innerHTML = `
             <div class="error-message">
                 <strong>Error:</strong> ${error.message}
@@ -51,6 +92,7 @@ function renderOrder(order) {
                     <div class="order-basic-info">
                         <div class="order-id">Order #${order.order_uid}</div>
                         <div class="order-meta">
+                            ${orderStream ? '<span class="live-indicator">● Live</span>' : ''}
                             <span>📦 ${order.track_number}</span>
                             <span>🏢 ${order.entry}</span>
                             <span>🕐 ${new Date(order.date_created).toLocaleString()}</span>
@@ -231,8 +273,8 @@ function determineOrderStatus(order) {
         return order.status;
     }
     
-    if (order.items.every(item => item.status === "delivered")) return 3;
-    if (order.items.some(item => item.status === "shipped")) return 2;
+    if (order.items.every(item => item.status === "delivered")) return 5;
+    if (order.items.some(item => item.status === "shipped")) return 4;
     return 1;
 }
 